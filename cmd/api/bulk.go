@@ -0,0 +1,273 @@
+// File: todo/cmd/api/bulk.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// The postTodoHandler() function serves POST /v1/todo/:id. httprouter will not let a
+// static "/v1/todo/bulk" route sit next to the ":id" wildcard, so the bulk endpoint is
+// picked out here by the parameter's value. No other todo id accepts a POST
+func (app *application) postTodoHandler(bulk http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if httprouter.ParamsFromContext(r.Context()).ByName("id") != "bulk" {
+			w.Header().Set("Allow", "GET, PATCH, DELETE, OPTIONS")
+			app.methodNotAllowedResponse(w, r)
+			return
+		}
+		bulk(w, r)
+	}
+}
+
+// maxBulkOperations caps the number of operations in a single bulk request
+const maxBulkOperations = 500
+
+// bulkOperation is one step of a bulk request. A create carries a todoInput in
+// Todo, an update carries a todoPatch and a delete only needs the ID. Version is
+// optional and, when given, must match the todo's current version
+type bulkOperation struct {
+	Op      string          `json:"op"`
+	ID      int64           `json:"id"`
+	Version int32           `json:"version"`
+	Todo    json.RawMessage `json:"todo"`
+}
+
+// bulkResult reports the outcome of one operation using the status code the
+// single item endpoint would have answered with
+type bulkResult struct {
+	Index  int               `json:"index"`
+	Op     string            `json:"op"`
+	Status int               `json:"status"`
+	Todo   *data.Todo        `json:"todo,omitempty"`
+	Next   *data.Todo        `json:"next,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// The bulkTodoHandler runs a batch of create, update and delete operations in one
+// transaction. By default the batch is all-or-nothing and any failure is answered
+// with the errors keyed by operation index. With "atomic": false each operation
+// succeeds or fails on its own and the response lists a result for every one
+func (app *application) bulkTodoHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Atomic     *bool           `json:"atomic"`
+		Operations []bulkOperation `json:"operations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Operations) > 0, "operations", "must contain at least one operation")
+	v.Check(len(input.Operations) <= maxBulkOperations, "operations", fmt.Sprintf("must not contain more than %d operations", maxBulkOperations))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	atomic := input.Atomic == nil || *input.Atomic
+
+	tx, err := app.models.Todos.Begin()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	results := make([]bulkResult, len(input.Operations))
+	failed := make(map[string]map[string]string)
+	for i, op := range input.Operations {
+		//Every operation gets a savepoint so a failed statement does not poison the
+		//transaction for the operations after it
+		err = tx.Savepoint()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		result, err := app.runBulkOperation(r, tx, op)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		result.Index = i
+		results[i] = result
+
+		if result.Errors != nil {
+			failed[strconv.Itoa(i)] = result.Errors
+			err = tx.RollbackToSavepoint()
+		} else {
+			err = tx.ReleaseSavepoint()
+		}
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	//In all-or-nothing mode a single failure discards the whole batch
+	if atomic && len(failed) > 0 {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, failed)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// runBulkOperation() carries out a single operation of a bulk request. Problems the
+// client can fix are reported in the result; the error is only for server failures
+func (app *application) runBulkOperation(r *http.Request, tx data.TodoBatch, op bulkOperation) (bulkResult, error) {
+	result := bulkResult{Op: op.Op}
+	fail := func(status int, key, message string) (bulkResult, error) {
+		result.Status = status
+		result.Errors = map[string]string{key: message}
+		return result, nil
+	}
+	scope := app.contextGetScope(r)
+	v := validator.New()
+
+	switch op.Op {
+	case "create":
+		var input todoInput
+		if len(op.Todo) == 0 {
+			return fail(http.StatusUnprocessableEntity, "todo", "must be provided")
+		}
+		if err := decodeBulkTodo(op.Todo, &input); err != nil {
+			return fail(http.StatusBadRequest, "todo", err.Error())
+		}
+		todo := input.newTodo(app.contextGetUser(r).ID, scope.WorkspaceID)
+
+		err := app.checkListAccess(v, r, todo.ListID)
+		if err != nil {
+			return result, err
+		}
+		if data.ValidateTodo(v, todo); !v.Valid() {
+			break
+		}
+
+		err = tx.Insert(todo)
+		if err != nil {
+			if errors.Is(err, data.ErrListNotFound) {
+				return fail(http.StatusUnprocessableEntity, "list_id", "list does not exist")
+			}
+			return result, err
+		}
+		result.Status = http.StatusCreated
+		result.Todo = todo
+
+	case "update":
+		todo, err := tx.Get(op.ID, scope)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				return fail(http.StatusNotFound, "id", "the requested resource could not be found")
+			}
+			return result, err
+		}
+		if !todo.Access.CanEdit() {
+			return fail(http.StatusForbidden, "id", "you do not have permission to edit this todo")
+		}
+		if op.Version != 0 && op.Version != todo.Version {
+			return fail(http.StatusConflict, "version", "the todo has changed since this version")
+		}
+
+		var input todoPatch
+		if len(op.Todo) > 0 {
+			if err := decodeBulkTodo(op.Todo, &input); err != nil {
+				return fail(http.StatusBadRequest, "todo", err.Error())
+			}
+		}
+		wasDone := todo.Status == data.StatusDone
+		input.apply(v, todo)
+		if input.ListID != nil {
+			err = app.checkListAccess(v, r, todo.ListID)
+			if err != nil {
+				return result, err
+			}
+		}
+		if data.ValidateTodo(v, todo); !v.Valid() {
+			break
+		}
+
+		next, err := saveTodo(tx, todo, scope, wasDone)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				return fail(http.StatusConflict, "version", "the todo has changed since this version")
+			case errors.Is(err, data.ErrListNotFound):
+				return fail(http.StatusUnprocessableEntity, "list_id", "list does not exist")
+			default:
+				return result, err
+			}
+		}
+		result.Status = http.StatusOK
+		result.Todo = todo
+		result.Next = next
+
+	case "delete":
+		todo, err := tx.Get(op.ID, scope)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				return fail(http.StatusNotFound, "id", "the requested resource could not be found")
+			}
+			return result, err
+		}
+		if todo.Access != data.AccessOwner {
+			return fail(http.StatusForbidden, "id", "you do not have permission to delete this todo")
+		}
+		if op.Version != 0 && op.Version != todo.Version {
+			return fail(http.StatusConflict, "version", "the todo has changed since this version")
+		}
+
+		err = tx.Delete(todo.ID, todo.Version, scope)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				return fail(http.StatusNotFound, "id", "the requested resource could not be found")
+			case errors.Is(err, data.ErrEditConflict):
+				return fail(http.StatusConflict, "version", "the todo has changed since this version")
+			default:
+				return result, err
+			}
+		}
+		result.Status = http.StatusOK
+
+	default:
+		v.AddError("op", "must be one of create, update or delete")
+	}
+
+	if !v.Valid() {
+		result.Status = http.StatusUnprocessableEntity
+		result.Errors = v.Errors
+	}
+	return result, nil
+}
+
+// decodeBulkTodo() decodes the todo of a single operation as strictly as readJSON() does
+func decodeBulkTodo(raw json.RawMessage, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err != nil {
+		return fmt.Errorf("todo is invalid: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// File: todo/cmd/api/claim.go
+package main
+
+import (
+	"errors"
+	"log"
+
+	"todo.kegodo.net/internal/data"
+)
+
+// runClaimTodos() carries out `api claim-todos EMAIL`. Todos created before user
+// accounts existed have no owner and no query can reach them; this gives them to
+// the user with that email address, in their personal workspace
+func runClaimTodos(cfg config, logger *log.Logger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: api claim-todos EMAIL")
+	}
+
+	db, err := openDB(&cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if cfg.db.driver != "postgres" {
+		return errors.New("claim-todos only applies to PostgreSQL databases")
+	}
+
+	models := data.NewModels(db)
+	user, err := models.Users.GetByEmail(args[0])
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return errors.New("no user has that email address")
+		}
+		return err
+	}
+	//an id of zero picks the workspace created when the user registered
+	workspace, err := models.Workspaces.GetForUser(0, user.ID)
+	if err != nil {
+		return err
+	}
+
+	claimed, err := data.TodoModel{DB: db}.Claim(data.Scope{UserID: user.ID, WorkspaceID: workspace.ID})
+	if err != nil {
+		return err
+	}
+	logger.Printf("gave %d todos to %s in workspace %q", claimed, user.Email, workspace.Name)
+	return nil
+}
@@ -0,0 +1,117 @@
+// File: todo/cmd/api/conditional.go
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo.kegodo.net/internal/data"
+)
+
+// The todoETag() function builds the strong entity tag for a todo from its id and
+// version. Checklist items, tag renames, deleting the todo's list and sharing change
+// the todo's body without bumping its version, so the checklist counts, tag names,
+// list id and the caller's access are hashed in as well
+func todoETag(todo *data.Todo) string {
+	h := sha256.New()
+	writeTodoState(h, todo)
+	return fmt.Sprintf(`"%d-%d-%x"`, todo.ID, todo.Version, h.Sum(nil)[:8])
+}
+
+// writeTodoState() writes everything an entity tag of the todo has to cover
+func writeTodoState(w io.Writer, todo *data.Todo) {
+	var listID int64
+	if todo.ListID != nil {
+		listID = *todo.ListID
+	}
+	fmt.Fprintf(w, "%d-%d/%d/%d/%q/%d/%s;", todo.ID, todo.Version, todo.Checklist.Done, todo.Checklist.Total, todo.Tags, listID, todo.Access)
+}
+
+// The todosETag() function builds the entity tag for a page of todos. It changes
+// whenever a todo on the page is added, removed or edited, or the page metadata moves
+func todosETag(todos []*data.Todo, metadata data.Metadata) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d/%d/%d;", metadata.CurrentPage, metadata.PageSize, metadata.TotalRecords)
+	for _, todo := range todos {
+		writeTodoState(h, todo)
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+}
+
+// The etagMatches() function reports whether etag appears in the comma separated list
+// of a conditional header. If-None-Match compares weakly, If-Match strongly
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// The notModified() method sets the ETag header and, when the client's If-None-Match
+// already names it, answers 304 Not Modified. It reports whether the response was written
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagMatches(header, etag, true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// The checkIfMatch() method enforces an If-Match header against the current ETag of
+// the resource, writing 412 Precondition Failed when it does not hold
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, etag, false) {
+		return true
+	}
+	app.preconditionFailedResponse(w, r)
+	return false
+}
+
+// The readExpectedVersion() method reads the version of a todo the client believes it is
+// modifying from the X-Expected-Version header. ok is false when the header is absent
+func (app *application) readExpectedVersion(r *http.Request) (version int32, ok bool, err error) {
+	value := r.Header.Get("X-Expected-Version")
+	if value == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 1 {
+		return 0, false, errors.New("X-Expected-Version header must be a positive integer")
+	}
+	return int32(n), true, nil
+}
+
+// The checkTodoVersion() method applies both If-Match and X-Expected-Version to a todo
+// about to be changed, writing the error response itself when either does not hold
+func (app *application) checkTodoVersion(w http.ResponseWriter, r *http.Request, todo *data.Todo) bool {
+	if !app.checkIfMatch(w, r, todoETag(todo)) {
+		return false
+	}
+	expected, ok, err := app.readExpectedVersion(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return false
+	}
+	if ok && expected != todo.Version {
+		app.editConflictResponse(w, r)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+// File: todo/cmd/api/conditional_test.go
+package main
+
+import (
+	"testing"
+
+	"todo.kegodo.net/internal/data"
+)
+
+func TestTodoETag(t *testing.T) {
+	list := int64(4)
+	base := data.Todo{ID: 7, Version: 3, Tags: []string{"home"}, Checklist: data.Checklist{Done: 1, Total: 2}, ListID: &list, Access: data.AccessView}
+	etag := todoETag(&base)
+
+	same := base
+	same.Tags = []string{"home"}
+	if got := todoETag(&same); got != etag {
+		t.Errorf("equal todos got different etags %s and %s", etag, got)
+	}
+
+	tests := []struct {
+		name   string
+		change func(todo *data.Todo)
+	}{
+		{name: "version", change: func(todo *data.Todo) { todo.Version++ }},
+		{name: "checklist item ticked", change: func(todo *data.Todo) { todo.Checklist.Done++ }},
+		{name: "checklist item added", change: func(todo *data.Todo) { todo.Checklist.Total++ }},
+		{name: "tag renamed", change: func(todo *data.Todo) { todo.Tags = []string{"house"} }},
+		{name: "tags merged", change: func(todo *data.Todo) { todo.Tags = nil }},
+		{name: "list deleted", change: func(todo *data.Todo) { todo.ListID = nil }},
+		{name: "access changed", change: func(todo *data.Todo) { todo.Access = data.AccessEdit }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := base
+			tt.change(&todo)
+			if got := todoETag(&todo); got == etag {
+				t.Errorf("etag %s did not change", got)
+			}
+			if got, old := todosETag([]*data.Todo{&todo}, data.Metadata{}), todosETag([]*data.Todo{&base}, data.Metadata{}); got == old {
+				t.Errorf("page etag %s did not change", got)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{header: `"a"`, want: true},
+		{header: `"b", "a"`, want: true},
+		{header: `*`, want: true},
+		{header: `"b"`, want: false},
+		{header: `W/"a"`, weak: true, want: true},
+		{header: `W/"a"`, weak: false, want: false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"a"`, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%s, weak %v) = %v, want %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
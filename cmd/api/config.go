@@ -0,0 +1,192 @@
+// File: todo/cmd/api/config.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"todo.kegodo.net/internal/validator"
+)
+
+// Settings come from, in increasing order of precedence: the defaults declared in
+// configFlags(), the JSON file named by -config, TODOS_* environment variables and
+// command-line flags. A setting has the same name everywhere, so -db-max-open-conns
+// is TODOS_DB_MAX_OPEN_CONNS in the environment and "db-max-open-conns" (or
+// "max-open-conns" inside a "db" object) in the file
+
+// Flags that choose what the program does rather than configure it
+var modeFlags = map[string]bool{"config": true, "print-config": true}
+
+// configFlags() declares every setting of cfg along with its default
+func configFlags(cfg *config) *flag.FlagSet {
+	fs := flag.NewFlagSet("api", flag.ExitOnError)
+
+	fs.IntVar(&cfg.port, "port", 4000, "API server port")
+	fs.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	fs.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN, or sqlite:path for SQLite")
+	fs.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
+	fs.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
+	fs.StringVar(&cfg.db.MaxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+	fs.BoolVar(&cfg.db.migrate, "db-migrate", false, "Apply pending database migrations at startup")
+	fs.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted todos can still be restored")
+	fs.BoolVar(&cfg.demo, "demo", false, "Serve todos from memory without a database")
+	fs.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "How long to wait for requests and background tasks at shutdown")
+	fs.String("config", "", "Path of a JSON config file")
+
+	return fs
+}
+
+// envName() gives the environment variable of a setting
+func envName(setting string) string {
+	return "TODOS_" + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// loadConfig() fills in the settings declared on fs from each source in turn
+func loadConfig(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	//flags given on the command line win over everything else
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	//lower precedence sources go first so that later ones overwrite them
+	path := fs.Lookup("config").Value.String()
+	if path == "" {
+		path = os.Getenv(envName("config"))
+	}
+	if path != "" {
+		settings, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(settings) {
+			if modeFlags[name] || fs.Lookup(name) == nil {
+				return fmt.Errorf("%s: unknown setting %q", path, name)
+			}
+			if explicit[name] {
+				continue
+			}
+			if err := fs.Set(name, settings[name]); err != nil {
+				return fmt.Errorf("%s: invalid %s: %w", path, name, err)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value := os.Getenv(envName(f.Name))
+		if err != nil || value == "" || explicit[f.Name] || modeFlags[f.Name] {
+			return
+		}
+		if e := fs.Set(f.Name, value); e != nil {
+			err = fmt.Errorf("invalid %s: %w", envName(f.Name), e)
+		}
+	})
+	return err
+}
+
+// readConfigFile() reads a JSON config file into setting names and their values.
+// Nested objects are flattened, so {"db": {"dsn": ""}} sets db-dsn
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err = dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := map[string]string{}
+	var flatten func(prefix string, values map[string]interface{})
+	flatten = func(prefix string, values map[string]interface{}) {
+		for key, value := range values {
+			if nested, ok := value.(map[string]interface{}); ok {
+				flatten(prefix+key+"-", nested)
+				continue
+			}
+			settings[prefix+key] = fmt.Sprint(value)
+		}
+	}
+	flatten("", values)
+	return settings, nil
+}
+
+// validateConfig() checks the settings once every source has been applied
+func validateConfig(cfg config) error {
+	v := validator.New()
+
+	v.Check(cfg.port > 0 && cfg.port <= 65535, "port", "must be between 1 and 65535")
+	v.Check(cfg.env != "", "env", "must be provided")
+	v.Check(cfg.demo || cfg.db.dsn != "", "db-dsn", "must be provided unless demo is set")
+	v.Check(cfg.db.maxOpenConns > 0, "db-max-open-conns", "must be greater than zero")
+	v.Check(cfg.db.maxIdleConns >= 0, "db-max-idle-conns", "must not be negative")
+	idleTime, err := time.ParseDuration(cfg.db.MaxIdleTime)
+	v.Check(err == nil && idleTime > 0, "db-max-idle-time", "must be a positive duration such as 15m")
+	v.Check(cfg.trash.retention > 0, "trash-retention", "must be greater than zero")
+	v.Check(cfg.shutdownTimeout > 0, "shutdown-timeout", "must be greater than zero")
+
+	if v.Valid() {
+		return nil
+	}
+	problems := make([]string, 0, len(v.Errors))
+	for _, key := range sortedKeys(v.Errors) {
+		problems = append(problems, key+" "+v.Errors[key])
+	}
+	return errors.New("invalid config: " + strings.Join(problems, "; "))
+}
+
+// printConfig() writes the effective settings as a JSON config file, with secrets redacted
+func printConfig(w io.Writer, fs *flag.FlagSet) error {
+	settings := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if !modeFlags[f.Name] {
+			settings[f.Name] = f.Value.String()
+		}
+	})
+	settings["db-dsn"] = redactDSN(settings["db-dsn"])
+
+	js, err := json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(js))
+	return err
+}
+
+// passwordRX finds the password of a key=value DSN
+var passwordRX = regexp.MustCompile(`(password=)('[^']*'|[^\s&]*)`)
+
+// redactDSN() hides the password in a URL or key=value DSN
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		dsn = u.Redacted()
+	}
+	return passwordRX.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
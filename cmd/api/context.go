@@ -0,0 +1,71 @@
+// File: todo/cmd/api/context.go
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"todo.kegodo.net/internal/data"
+)
+
+// Define a custom type for our context keys so they cannot collide with other packages
+type contextKey string
+
+const (
+	userContextKey      = contextKey("user")
+	workspaceContextKey = contextKey("workspace")
+	apiKeyContextKey    = contextKey("api_key")
+)
+
+// contextSetUser() returns a copy of the request with the user added to its context
+func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser() retrieves the user from the request context. It is only called
+// after the authenticate middleware has run, so a missing user is a bug
+func (app *application) contextGetUser(r *http.Request) *data.User {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
+
+// contextSetWorkspace() returns a copy of the request with the selected workspace added to its context
+func (app *application) contextSetWorkspace(r *http.Request, workspace *data.Workspace) *http.Request {
+	ctx := context.WithValue(r.Context(), workspaceContextKey, workspace)
+	return r.WithContext(ctx)
+}
+
+// contextGetWorkspace() retrieves the selected workspace from the request context.
+// It is only called after the requireWorkspace middleware has run
+func (app *application) contextGetWorkspace(r *http.Request) *data.Workspace {
+	workspace, ok := r.Context().Value(workspaceContextKey).(*data.Workspace)
+	if !ok {
+		panic("missing workspace value in request context")
+	}
+	return workspace
+}
+
+// contextGetScope() combines the user and workspace of the request for the todo queries
+func (app *application) contextGetScope(r *http.Request) data.Scope {
+	return data.Scope{
+		UserID:      app.contextGetUser(r).ID,
+		WorkspaceID: app.contextGetWorkspace(r).ID,
+	}
+}
+
+// contextSetAPIKey() returns a copy of the request with the API key it was authenticated by
+func (app *application) contextSetAPIKey(r *http.Request, key *data.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey() retrieves the API key the request was authenticated by, or nil
+// when the request used a session token or no authentication at all
+func (app *application) contextGetAPIKey(r *http.Request) *data.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*data.APIKey)
+	return key
+}
@@ -0,0 +1,52 @@
+// File: todo/cmd/api/demo.go
+package main
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"todo.kegodo.net/internal/data"
+)
+
+// In single-user mode every request acts as this user in this workspace
+var (
+	demoUser      = &data.User{ID: 1, Name: "Demo", Email: "demo@example.com"}
+	demoWorkspace = &data.Workspace{ID: 1, Name: "Demo", Role: data.RoleOwner}
+)
+
+// singleUser() reports whether the API runs without accounts, either in demo mode or
+// on the SQLite store
+func (app *application) singleUser() bool {
+	return app.config.demo || app.config.db.driver == "sqlite"
+}
+
+// demoRoutes() serves the todo endpoints for single-user mode, from memory or SQLite.
+// Users, lists, shares and history need Postgres and are left out
+func (app *application) demoRoutes() http.Handler {
+	router := httprouter.New()
+
+	router.NotFound = http.HandlerFunc(app.notFoundReponse)
+	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+
+	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/todo", app.listTododHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/todo", app.createTodoHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/todo/:id", app.showTodoHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/todo/:id", app.updateTodoHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/todo/:id", app.deleteTodoHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id", app.postTodoHandler(app.bulkTodoHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id/restore", app.restoreTodoHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/trash", app.listTrashHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/trash/:id", app.purgeTodoHandler)
+
+	return app.demoIdentity(router)
+}
+
+// The demoIdentity() middleware stands in for authenticate() and requireWorkspace() in single-user mode
+func (app *application) demoIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = app.contextSetUser(r, demoUser)
+		r = app.contextSetWorkspace(r, demoWorkspace)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,313 @@
+// File: todo/cmd/api/demo_test.go
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo.kegodo.net/internal/data"
+)
+
+// newTestServer() serves the single-user routes from an empty in-memory store
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	app := &application{
+		logger: log.New(io.Discard, "", 0),
+		models: data.Models{Todos: data.NewMemoryTodoStore()},
+		done:   make(chan struct{}),
+	}
+	app.config.demo = true
+	srv := httptest.NewServer(app.routes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testResponse is a response with its JSON body decoded
+type testResponse struct {
+	status int
+	header http.Header
+	body   map[string]json.RawMessage
+}
+
+// send() makes a request to the test server, headers are given as name, value pairs
+func send(t *testing.T, srv *httptest.Server, method, path, body string, headers ...string) testResponse {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	resp := testResponse{status: res.StatusCode, header: res.Header}
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &resp.body); err != nil {
+			t.Fatalf("%s %s answered %s", method, path, raw)
+		}
+	}
+	return resp
+}
+
+// todo() decodes the todo of the response
+func (resp testResponse) todo(t *testing.T) data.Todo {
+	t.Helper()
+	var todo data.Todo
+	if err := decodeField(resp, "todo", &todo); err != nil {
+		t.Fatalf("response has no todo: %v", resp.body)
+	}
+	return todo
+}
+
+// decodeField() decodes one field of the response's JSON envelope
+func decodeField(resp testResponse, key string, dst interface{}) error {
+	raw, ok := resp.body[key]
+	if !ok {
+		return fmt.Errorf("response has no %q", key)
+	}
+	return json.Unmarshal(raw, dst)
+}
+
+func TestCreateAndShowTodo(t *testing.T) {
+	srv := newTestServer(t)
+
+	created := send(t, srv, http.MethodPost, "/v1/todo", `{"title": "Pay rent", "tags": ["Home", "bills"]}`)
+	if created.status != http.StatusCreated {
+		t.Fatalf("create answered %d %v", created.status, created.body)
+	}
+	todo := created.todo(t)
+	if want := fmt.Sprintf("/v1/todo/%d", todo.ID); created.header.Get("Location") != want {
+		t.Errorf("Location = %q, want %q", created.header.Get("Location"), want)
+	}
+	if todo.Status != data.StatusTodo || todo.Priority != data.PriorityNormal {
+		t.Errorf("new todo has status %q and priority %q", todo.Status, todo.Priority)
+	}
+	if got := strings.Join(todo.Tags, ","); got != "bills,home" {
+		t.Errorf("tags = %s, want bills,home", got)
+	}
+
+	shown := send(t, srv, http.MethodGet, fmt.Sprintf("/v1/todo/%d", todo.ID), "")
+	if shown.status != http.StatusOK || shown.todo(t).Title != "Pay rent" {
+		t.Fatalf("show answered %d %v", shown.status, shown.body)
+	}
+	etag := shown.header.Get("ETag")
+	if etag == "" || etag != created.header.Get("ETag") {
+		t.Errorf("show ETag %q does not match create ETag %q", etag, created.header.Get("ETag"))
+	}
+
+	cached := send(t, srv, http.MethodGet, fmt.Sprintf("/v1/todo/%d", todo.ID), "", "If-None-Match", etag)
+	if cached.status != http.StatusNotModified {
+		t.Errorf("If-None-Match answered %d, want 304", cached.status)
+	}
+
+	if missing := send(t, srv, http.MethodGet, "/v1/todo/999", ""); missing.status != http.StatusNotFound {
+		t.Errorf("unknown todo answered %d, want 404", missing.status)
+	}
+}
+
+func TestCreateTodoRejectsBadInput(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "malformed json", body: `{"title": `, status: http.StatusBadRequest},
+		{name: "unknown field", body: `{"title": "a", "colour": "red"}`, status: http.StatusBadRequest},
+		{name: "missing title", body: `{"description": "no title"}`, status: http.StatusUnprocessableEntity},
+		{name: "unknown status", body: `{"title": "a", "status": "someday"}`, status: http.StatusUnprocessableEntity},
+		{name: "bad recurrence", body: `{"title": "a", "recurrence": "FREQ=HOURLY"}`, status: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := send(t, srv, http.MethodPost, "/v1/todo", tt.body); resp.status != tt.status {
+				t.Errorf("create answered %d %v, want %d", resp.status, resp.body, tt.status)
+			}
+		})
+	}
+}
+
+func TestUpdateTodo(t *testing.T) {
+	srv := newTestServer(t)
+
+	created := send(t, srv, http.MethodPost, "/v1/todo", `{"title": "Fix the bike", "status": "blocked", "due_at": "2030-01-01T09:00:00Z"}`)
+	todo := created.todo(t)
+	path := fmt.Sprintf("/v1/todo/%d", todo.ID)
+	etag := created.header.Get("ETag")
+
+	if resp := send(t, srv, http.MethodPatch, path, `{"title": "x"}`, "If-Match", `"stale"`); resp.status != http.StatusPreconditionFailed {
+		t.Errorf("stale If-Match answered %d, want 412", resp.status)
+	}
+	if resp := send(t, srv, http.MethodPatch, path, `{"title": "x"}`, "X-Expected-Version", "5"); resp.status != http.StatusConflict {
+		t.Errorf("wrong X-Expected-Version answered %d, want 409", resp.status)
+	}
+	if resp := send(t, srv, http.MethodPatch, path, `{"status": "done"}`); resp.status != http.StatusUnprocessableEntity {
+		t.Errorf("blocked to done answered %d, want 422", resp.status)
+	}
+
+	updated := send(t, srv, http.MethodPatch, path, `{"title": "Fix the bike tyre", "due_at": null}`, "If-Match", etag)
+	if updated.status != http.StatusOK {
+		t.Fatalf("update answered %d %v", updated.status, updated.body)
+	}
+	got := updated.todo(t)
+	if got.Title != "Fix the bike tyre" || got.DueAt != nil || got.Version != todo.Version+1 {
+		t.Errorf("updated todo is %q due %v version %d", got.Title, got.DueAt, got.Version)
+	}
+	if updated.header.Get("ETag") == etag {
+		t.Error("update kept the old ETag")
+	}
+	//the old ETag no longer matches the changed todo
+	if resp := send(t, srv, http.MethodPatch, path, `{"title": "y"}`, "If-Match", etag); resp.status != http.StatusPreconditionFailed {
+		t.Errorf("replayed If-Match answered %d, want 412", resp.status)
+	}
+}
+
+func TestDeleteRestoreAndPurgeTodo(t *testing.T) {
+	srv := newTestServer(t)
+
+	todo := send(t, srv, http.MethodPost, "/v1/todo", `{"title": "Old note"}`).todo(t)
+	path := fmt.Sprintf("/v1/todo/%d", todo.ID)
+
+	if resp := send(t, srv, http.MethodDelete, path, ""); resp.status != http.StatusOK {
+		t.Fatalf("delete answered %d %v", resp.status, resp.body)
+	}
+	if resp := send(t, srv, http.MethodGet, path, ""); resp.status != http.StatusNotFound {
+		t.Errorf("deleted todo answered %d, want 404", resp.status)
+	}
+
+	trash := send(t, srv, http.MethodGet, "/v1/trash", "")
+	var trashed []data.Todo
+	if err := json.Unmarshal(trash.body["todos"], &trashed); err != nil || len(trashed) != 1 || trashed[0].ID != todo.ID {
+		t.Fatalf("trash answered %d %v", trash.status, trash.body)
+	}
+
+	if resp := send(t, srv, http.MethodPost, path+"/restore", ""); resp.status != http.StatusOK {
+		t.Fatalf("restore answered %d %v", resp.status, resp.body)
+	}
+	if resp := send(t, srv, http.MethodGet, path, ""); resp.status != http.StatusOK {
+		t.Errorf("restored todo answered %d, want 200", resp.status)
+	}
+
+	send(t, srv, http.MethodDelete, path, "")
+	if resp := send(t, srv, http.MethodDelete, fmt.Sprintf("/v1/trash/%d", todo.ID), ""); resp.status != http.StatusOK {
+		t.Fatalf("purge answered %d %v", resp.status, resp.body)
+	}
+	if resp := send(t, srv, http.MethodPost, path+"/restore", ""); resp.status != http.StatusNotFound {
+		t.Errorf("restoring a purged todo answered %d, want 404", resp.status)
+	}
+}
+
+func TestBulkTodoIsAllOrNothing(t *testing.T) {
+	srv := newTestServer(t)
+
+	body := `{"operations": [
+		{"op": "create", "todo": {"title": "First"}},
+		{"op": "create", "todo": {"title": ""}}
+	]}`
+	resp := send(t, srv, http.MethodPost, "/v1/todo/bulk", body)
+	if resp.status != http.StatusUnprocessableEntity {
+		t.Fatalf("bulk answered %d %v, want 422", resp.status, resp.body)
+	}
+	var errs map[string]map[string]string
+	if err := json.Unmarshal(resp.body["error"], &errs); err != nil || errs["1"]["title"] == "" {
+		t.Errorf("bulk errors = %s, want one for operation 1", resp.body["error"])
+	}
+
+	list := send(t, srv, http.MethodGet, "/v1/todo", "")
+	var todos []data.Todo
+	if err := json.Unmarshal(list.body["todos"], &todos); err != nil || len(todos) != 0 {
+		t.Errorf("failed batch left %d todos behind", len(todos))
+	}
+
+	//the bulk route shares its path with single todos, which take no POST
+	if resp := send(t, srv, http.MethodPost, "/v1/todo/1", body); resp.header.Get("Allow") == "" {
+		t.Errorf("POST to a todo answered %d without an Allow header", resp.status)
+	}
+}
+
+func TestListTodosRejectsBadCursor(t *testing.T) {
+	srv := newTestServer(t)
+	send(t, srv, http.MethodPost, "/v1/todo", `{"title": "first"}`)
+	send(t, srv, http.MethodPost, "/v1/todo", `{"title": "second"}`)
+
+	//cursor() encodes a cursor the way the data package does
+	cursor := func(js string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(js))
+	}
+
+	first := send(t, srv, http.MethodGet, "/v1/todo?sort=title&limit=1", "")
+	var metadata data.Metadata
+	if err := decodeField(first, "metadata", &metadata); err != nil || metadata.NextCursor == "" {
+		t.Fatalf("first page has no next cursor: %v", first.body)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "next page", query: "sort=title&limit=1&cursor=" + metadata.NextCursor, status: http.StatusOK},
+		{name: "not base64", query: "sort=title&cursor=%25%25", status: http.StatusUnprocessableEntity},
+		{name: "not json", query: "sort=title&cursor=" + cursor(`garbage`), status: http.StatusUnprocessableEntity},
+		{name: "other sort", query: "sort=-title&cursor=" + metadata.NextCursor, status: http.StatusUnprocessableEntity},
+		{name: "missing values", query: "sort=title&cursor=" + cursor(`{"s":"title","v":["first"]}`), status: http.StatusUnprocessableEntity},
+		{name: "extra values", query: "sort=id&cursor=" + cursor(`{"s":"id","v":["1","2"]}`), status: http.StatusUnprocessableEntity},
+		{name: "bad id", query: "sort=title&cursor=" + cursor(`{"s":"title","v":["first","one"]}`), status: http.StatusUnprocessableEntity},
+		{name: "bad due date", query: "sort=priority&cursor=" + cursor(`{"s":"priority","v":["2","soon","1"]}`), status: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, srv, http.MethodGet, "/v1/todo?"+tt.query, "")
+			if resp.status != tt.status {
+				t.Errorf("list answered %d %v, want %d", resp.status, resp.body, tt.status)
+			}
+		})
+	}
+}
+
+func TestListTodosSortsByPriorityThenDueDate(t *testing.T) {
+	srv := newTestServer(t)
+	for _, body := range []string{
+		`{"title": "low", "priority": "low"}`,
+		`{"title": "high, no due date", "priority": "high"}`,
+		`{"title": "high, due later", "priority": "high", "due_at": "2030-02-01T00:00:00Z"}`,
+		`{"title": "urgent", "priority": "urgent"}`,
+		`{"title": "high, due sooner", "priority": "high", "due_at": "2030-01-01T00:00:00Z"}`,
+		`{"title": "normal"}`,
+	} {
+		if resp := send(t, srv, http.MethodPost, "/v1/todo", body); resp.status != http.StatusCreated {
+			t.Fatalf("create answered %d %v", resp.status, resp.body)
+		}
+	}
+
+	resp := send(t, srv, http.MethodGet, "/v1/todo", "")
+	var todos []data.Todo
+	if err := decodeField(resp, "todos", &todos); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+	want := []string{"urgent", "high, due sooner", "high, due later", "high, no due date", "normal", "low"}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Errorf("default order is %q, want %q", titles, want)
+	}
+}
@@ -0,0 +1,52 @@
+// File: todo/cmd/api/grant.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// runGrantPermission() carries out `api grant-permission EMAIL CODE...`. Registration
+// only hands out todos:read and todos:write, so this is how an operator makes a
+// user an admin who may rename and merge tags
+func runGrantPermission(cfg config, logger *log.Logger, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: api grant-permission EMAIL CODE...")
+	}
+	codes := args[1:]
+	for _, code := range codes {
+		if !validator.In(code, data.PermissionTodosRead, data.PermissionTodosWrite, data.PermissionTodosAdmin) {
+			return fmt.Errorf("unknown permission %q, must be todos:read, todos:write or todos:admin", code)
+		}
+	}
+
+	db, err := openDB(&cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if cfg.db.driver != "postgres" {
+		return errors.New("grant-permission only applies to PostgreSQL databases")
+	}
+
+	models := data.NewModels(db)
+	user, err := models.Users.GetByEmail(args[0])
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return errors.New("no user has that email address")
+		}
+		return err
+	}
+
+	err = models.Permissions.AddForUser(user.ID, codes...)
+	if err != nil {
+		return err
+	}
+	logger.Printf("granted %s to %s", strings.Join(codes, ", "), user.Email)
+	return nil
+}
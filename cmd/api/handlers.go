@@ -2,21 +2,133 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"todo.kegodo.net/internal/data"
 	"todo.kegodo.net/internal/validator"
 )
 
+// todoInput is the body a client sends to create a todo element
+type todoInput struct {
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Status      data.Status   `json:"status"`
+	Priority    data.Priority `json:"priority"`
+	StartsAt    *time.Time    `json:"starts_at"`
+	DueAt       *time.Time    `json:"due_at"`
+	Tags        []string      `json:"tags"`
+	ListID      *int64        `json:"list_id"`
+	Recurrence  string        `json:"recurrence"`
+}
+
+// newTodo() fills in the defaults for a new todo element owned by the given user
+func (input todoInput) newTodo(ownerID, workspaceID int64) *data.Todo {
+	//new todo elements start in the todo status unless told otherwise
+	if input.Status == "" {
+		input.Status = data.StatusTodo
+	}
+	//as do they start with a normal priority
+	if input.Priority == 0 {
+		input.Priority = data.PriorityNormal
+	}
+
+	//list 0 means the todo does not belong to a list
+	if input.ListID != nil && *input.ListID == 0 {
+		input.ListID = nil
+	}
+
+	//coping the valeus from the input struct to the new todo struct
+	return &data.Todo{
+		Title:       input.Title,
+		Description: input.Description,
+		Status:      input.Status,
+		Priority:    input.Priority,
+		StartsAt:    input.StartsAt,
+		DueAt:       input.DueAt,
+		Tags:        data.NormalizeTags(input.Tags),
+		ListID:      input.ListID,
+		Recurrence:  input.Recurrence,
+		OwnerID:     ownerID,
+		WorkspaceID: workspaceID,
+	}
+}
+
+// todoPatch is the body a client sends to change a todo element.
+// The fields are pointers because pointers have a default value of nil
+type todoPatch struct {
+	Title       *string        `json:"title"`
+	Description *string        `json:"description"`
+	Status      *data.Status   `json:"status"`
+	Priority    *data.Priority `json:"priority"`
+	StartsAt    nullableTime   `json:"starts_at"`
+	DueAt       nullableTime   `json:"due_at"`
+	Tags        []string       `json:"tags"`
+	ListID      *int64         `json:"list_id"`
+	Recurrence  *string        `json:"recurrence"`
+}
+
+// nullableTime tells a date sent as null, which clears it, apart from one that was not sent
+type nullableTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+func (t *nullableTime) UnmarshalJSON(js []byte) error {
+	t.Set = true
+	t.Time = nil
+	if string(js) == "null" {
+		return nil
+	}
+	return json.Unmarshal(js, &t.Time)
+}
+
+// apply() copies the fields that were sent onto the todo. Status changes must
+// follow the allowed workflow
+func (input todoPatch) apply(v *validator.Validator, todo *data.Todo) {
+	//checking for any updates
+	if input.Title != nil {
+		todo.Title = *input.Title
+	}
+	if input.Description != nil {
+		todo.Description = *input.Description
+	}
+	if input.Priority != nil {
+		todo.Priority = *input.Priority
+	}
+	//a date sent as null is cleared
+	if input.StartsAt.Set {
+		todo.StartsAt = input.StartsAt.Time
+	}
+	if input.DueAt.Set {
+		todo.DueAt = input.DueAt.Time
+	}
+	if input.Tags != nil {
+		todo.Tags = data.NormalizeTags(input.Tags)
+	}
+	//Moving a todo to list 0 takes it out of its list
+	if input.ListID != nil {
+		todo.ListID = input.ListID
+		if *input.ListID == 0 {
+			todo.ListID = nil
+		}
+	}
+	if input.Recurrence != nil {
+		todo.Recurrence = *input.Recurrence
+	}
+	if input.Status != nil {
+		data.ValidateStatusTransition(v, todo.Status, *input.Status)
+		todo.Status = *input.Status
+	}
+}
+
 func (app *application) createTodoHandler(w http.ResponseWriter, r *http.Request) {
 	//Our target decode destination
-	var input struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Done        string `json:"done"`
-	}
+	var input todoInput
 
 	//Initialize a new json.Decoder instance
 	err := app.readJSON(w, r, &input)
@@ -25,16 +137,18 @@ func (app *application) createTodoHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	//coping the valeus from the input struct to the new todo struct
-	todo := &data.Todo{
-		Title:       input.Title,
-		Description: input.Description,
-		Done:        input.Done,
-	}
+	todo := input.newTodo(app.contextGetUser(r).ID, app.contextGetWorkspace(r).ID)
 
 	//Initialize a new Validator Instance
 	v := validator.New()
 
+	//the todo can only go on a list the user may edit
+	err = app.checkListAccess(v, r, todo.ListID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	//check the map to determine if ther were any validation errors
 	if data.ValidateTodo(v, todo); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -44,12 +158,20 @@ func (app *application) createTodoHandler(w http.ResponseWriter, r *http.Request
 	//Creating a todo element
 	err = app.models.Todos.Insert(todo)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrListNotFound):
+			v.AddError("list_id", "list does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
 	}
 
 	//Create a location header for the newly created resource
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/v1/toto/%d", todo.ID))
+	headers.Set("Location", fmt.Sprintf("/v1/todo/%d", todo.ID))
+	headers.Set("ETag", todoETag(todo))
 
 	err = app.writeJSON(w, http.StatusCreated, envelope{"todo": todo}, headers)
 	if err != nil {
@@ -59,29 +181,19 @@ func (app *application) createTodoHandler(w http.ResponseWriter, r *http.Request
 
 // The showentry handler will display an individual todo element
 func (app *application) showTodoHandler(w http.ResponseWriter, r *http.Request) {
-	//getting the request data from param function
-	id, err := app.readIDParam(r)
-	if err != nil {
-		app.notFoundReponse(w, r)
+	//Fetching the specific todo element
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
 		return
 	}
 
-	//Fetching the specific todo element
-	todo, err := app.models.Todos.Get(id)
-
-	//Handling errors
-	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundReponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
-		}
+	//The client's cached copy is still current
+	if app.notModified(w, r, todoETag(todo)) {
 		return
 	}
 
 	//Writing the data from the returned get()
-	err = app.writeJSON(w, http.StatusOK, envelope{"todo": todo}, nil)
+	err := app.writeJSON(w, http.StatusOK, envelope{"todo": todo}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -89,94 +201,136 @@ func (app *application) showTodoHandler(w http.ResponseWriter, r *http.Request)
 
 // Facilitates an update action to the todo element in the database
 func (app *application) updateTodoHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
-	if err != nil {
-		app.notFoundReponse(w, r)
+	//Fetch the original record from the database
+	todo, ok := app.fetchEditableTodo(w, r)
+	if !ok {
 		return
 	}
 
-	//Fetch the original record from the database
-	todo, err := app.models.Todos.Get(id)
-
-	//Handling the errors
-	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundReponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
-		}
+	//If the client said which version it is editing, it must still be the current one
+	if !app.checkTodoVersion(w, r, todo) {
 		return
 	}
 
 	//Creating an input struct to hold data read in from the client
-	//Updating the input struct to use pointers because pointers have a default value of nil
-	var input struct {
-		Title       *string `json:"title"`
-		Description *string `json:"description"`
-		Done        *string `json:"done"`
-	}
+	var input todoPatch
 
 	//Initilizing a new json.Decoder instance
-	err = app.readJSON(w, r, &input)
+	err := app.readJSON(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	//checking for any updates
-	if input.Title != nil {
-		todo.Title = *input.Title
-	}
-	if input.Description != nil {
-		todo.Description = *input.Description
-	}
-	if input.Done != nil {
-		todo.Done = *input.Done
-	}
-
 	//Initilize a new Validator Instance
 	v := validator.New()
 
+	//checking for any updates
+	wasDone := todo.Status == data.StatusDone
+	input.apply(v, todo)
+
+	//A todo moving to another list can only go on a list the user may edit
+	if input.ListID != nil {
+		err = app.checkListAccess(v, r, todo.ListID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	//Checking the map to determin if there were any validation errors
 	if data.ValidateTodo(v, todo); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	//Passing the updated todo element to the update() method
-	err = app.models.Todos.Update(todo)
+	//Passing the updated todo element to the update() method, together with the
+	//next occurrence when it completes a recurring todo
+	tx, err := app.models.Todos.Begin()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	next, err := saveTodo(tx, todo, app.contextGetScope(r), wasDone)
+	if err == nil {
+		err = tx.Commit()
+	}
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrListNotFound):
+			v.AddError("list_id", "list does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	env := envelope{"todo": todo}
+	if next != nil {
+		env["next"] = next
+	}
+
 	//Writing the data returned by Get()
-	err = app.writeJSON(w, http.StatusOK, envelope{"todo": todo}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", todoETag(todo))
+	err = app.writeJSON(w, http.StatusOK, env, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// saveTodo() writes a changed todo as part of tx. The first time a recurring todo is
+// completed its next occurrence is created too and linked through next_id, so that
+// reopening and completing it again does not schedule a second one
+func saveTodo(tx data.TodoBatch, todo *data.Todo, scope data.Scope, wasDone bool) (*data.Todo, error) {
+	var next *data.Todo
+	if !wasDone && todo.Status == data.StatusDone && todo.NextID == nil {
+		if occurrence, ok := todo.NextOccurrence(); ok {
+			err := tx.Insert(occurrence)
+			if err != nil {
+				return nil, err
+			}
+			todo.NextID = &occurrence.ID
+			next = occurrence
+		}
+	}
+	err := tx.Update(todo, scope)
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
 // To facilitate deletion of a todo element
 func (app *application) deleteTodoHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
-	if err != nil {
-		app.notFoundReponse(w, r)
+	//Only the owner may delete a todo element
+	todo, ok := app.fetchOwnedTodo(w, r)
+	if !ok {
+		return
+	}
+	if !app.checkIfMatch(w, r, todoETag(todo)) {
 		return
 	}
 
-	err = app.models.Todos.Delete(id)
+	//The delete only goes through while the todo is still at the version that was
+	//checked, so a write landing in between cannot slip past If-Match
+	err := app.models.Todos.Delete(todo.ID, todo.Version, app.contextGetScope(r))
 
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundReponse(w, r)
+		case errors.Is(err, data.ErrEditConflict) && r.Header.Get("If-Match") != "":
+			app.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -184,7 +338,7 @@ func (app *application) deleteTodoHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	//Returning 200 status ok to the client with a success message
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "todo element sucessfully deleted"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "todo element moved to the trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -192,43 +346,33 @@ func (app *application) deleteTodoHandler(w http.ResponseWriter, r *http.Request
 
 // The listTodo handler allows the client to see a listing of todo elements based on a set of criteria
 func (app *application) listTododHandler(w http.ResponseWriter, r *http.Request) {
-	//creating an input struct to hold our query parameters
-	var input struct {
-		Title       string
-		Description string
-		Done        string
-		data.Filters
-	}
-
 	//Initializing a validator
 	v := validator.New()
 
-	//getting the URL values map
-	qs := r.URL.Query()
-
-	//Using the helper method to extract the values
-	input.Title = app.readString(qs, "title", "")
-	input.Description = app.readString(qs, "decription", "")
-	input.Done = app.readString(qs, "done", "")
-
-	//Get the page information
-	input.Filters.Page = app.readInt(qs, "page", 1, v)
-	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	//Get the sort information
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	// Specific the allowed sort values
-	input.Filters.SortList = []string{"id", "title", "description", "done", "-id", "-title", "-description", "-done"}
+	//Reading the search criteria and page information from the query string
+	search, filters := app.readTodoSearch(r.URL.Query(), v)
 
 	//checking for validation errors
-	if data.ValidateFilter(v, input.Filters); !v.Valid() {
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
 	//Geting a listing of all todo elements
-	tasks, metadata, err := app.models.Todos.GetAll(input.Title, input.Description, input.Done, input.Filters)
+	tasks, metadata, err := app.models.Todos.GetAll(app.contextGetScope(r), search, filters)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidCursor):
+			v.AddError("cursor", "invalid cursor for this sort")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	//Polling clients get a 304 while the page is unchanged
+	if app.notModified(w, r, todosETag(tasks, metadata)) {
 		return
 	}
 
@@ -239,3 +383,114 @@ func (app *application) listTododHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 }
+
+// fetchTodo() loads the todo named by the ":id" route parameter and writes the
+// error response itself when it cannot be found. Todos the user has not been
+// given access to are reported as not found
+func (app *application) fetchTodo(w http.ResponseWriter, r *http.Request) (*data.Todo, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return nil, false
+	}
+
+	todo, err := app.models.Todos.Get(id, app.contextGetScope(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return todo, true
+}
+
+// fetchEditableTodo() works like fetchTodo() but also rejects users who may only view the todo
+func (app *application) fetchEditableTodo(w http.ResponseWriter, r *http.Request) (*data.Todo, bool) {
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !todo.Access.CanEdit() {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+	return todo, true
+}
+
+// checkListAccess() adds a validation error when the user cannot put todos on the list
+func (app *application) checkListAccess(v *validator.Validator, r *http.Request, listID *int64) error {
+	//there are no lists in single-user mode, the store itself rejects list ids
+	if listID == nil || *listID < 1 || app.singleUser() {
+		return nil
+	}
+	list, err := app.models.Lists.Get(*listID, app.contextGetScope(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("list_id", "list does not exist")
+			return nil
+		default:
+			return err
+		}
+	}
+	v.Check(list.Access.CanEdit(), "list_id", "you may not add todos to this list")
+	return nil
+}
+
+// readTodoSearch() extracts and validates the criteria shared by every todo listing
+func (app *application) readTodoSearch(qs url.Values, v *validator.Validator) (data.TodoSearch, data.Filters) {
+	//creating an input struct to hold our query parameters
+	var input struct {
+		data.TodoSearch
+		data.Filters
+	}
+
+	//Using the helper method to extract the values
+	input.Title = app.readString(qs, "title", "")
+	input.Description = app.readString(qs, "description", "")
+	input.Status = app.readString(qs, "status", "")
+	if priority := app.readString(qs, "priority", ""); priority != "" {
+		p, err := data.ParsePriority(priority)
+		if err != nil {
+			v.AddError("priority", "invalid priority value")
+		}
+		input.Priority = p
+	}
+	input.DueBefore = app.readTime(qs, "due_before", v)
+	input.DueAfter = app.readTime(qs, "due_after", v)
+	input.Overdue = app.readBool(qs, "overdue", false, v)
+	input.Tags = data.NormalizeTags(app.readCSV(qs, "tags", []string{}))
+	tagsMatch := app.readString(qs, "tags_match", "any")
+	v.Check(validator.In(tagsMatch, "any", "all"), "tags_match", "must be either any or all")
+	input.AllTags = tagsMatch == "all"
+	input.ListID = int64(app.readInt(qs, "list_id", 0, v))
+
+	//Get the page information. Sending a cursor or a limit switches to cursor paging,
+	//where an empty cursor asks for the first page
+	input.Filters.UseCursor = qs.Has("cursor") || qs.Has("limit")
+	if input.Filters.UseCursor {
+		v.Check(!qs.Has("page") && !qs.Has("page_size"), "cursor", "cannot be combined with page or page_size")
+		input.Filters.Cursor = app.readString(qs, "cursor", "")
+		input.Filters.PageSize = app.readInt(qs, "limit", 20, v)
+	} else {
+		input.Filters.Page = app.readInt(qs, "page", 1, v)
+		input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	}
+	//Get the sort information. By default the most urgent todos come first and,
+	//within a priority, the ones due soonest
+	input.Filters.Sort = app.readString(qs, "sort", "-priority")
+	// Specific the allowed sort values
+	input.Filters.SortList = []string{"id", "title", "description", "status", "priority", "due_at", "starts_at", "-id", "-title", "-description", "-status", "-priority", "-due_at", "-starts_at"}
+
+	//checking for validation errors
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, data.Statuses...), "status", "invalid status value")
+	}
+	v.Check(input.ListID >= 0, "list_id", "must not be negative")
+	data.ValidateFilter(v, input.Filters)
+
+	return input.TodoSearch, input.Filters
+}
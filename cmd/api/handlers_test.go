@@ -0,0 +1,74 @@
+// File: todo/cmd/api/handlers_test.go
+package main
+
+import (
+	"testing"
+	"time"
+
+	"todo.kegodo.net/internal/data"
+)
+
+func TestSaveTodoSchedulesNextOccurrenceOnce(t *testing.T) {
+	store := data.NewMemoryTodoStore()
+	scope := data.Scope{UserID: 1, WorkspaceID: 1}
+	due := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	todo := &data.Todo{
+		Title:       "Water the plants",
+		Status:      data.StatusTodo,
+		Priority:    data.PriorityNormal,
+		DueAt:       &due,
+		Recurrence:  "FREQ=DAILY",
+		OwnerID:     scope.UserID,
+		WorkspaceID: scope.WorkspaceID,
+	}
+	if err := store.Insert(todo); err != nil {
+		t.Fatal(err)
+	}
+
+	//save moves the todo to a status the way the update handler does
+	save := func(status data.Status) *data.Todo {
+		t.Helper()
+		wasDone := todo.Status == data.StatusDone
+		todo.Status = status
+		tx, err := store.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		next, err := saveTodo(tx, todo, scope, wasDone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		return next
+	}
+
+	next := save(data.StatusDone)
+	if next == nil {
+		t.Fatal("completing a recurring todo did not schedule the next occurrence")
+	}
+	if want := due.AddDate(0, 0, 1); !next.DueAt.Equal(want) {
+		t.Errorf("next occurrence due %v, want %v", next.DueAt, want)
+	}
+	if todo.NextID == nil || *todo.NextID != next.ID {
+		t.Errorf("todo next_id = %v, want %d", todo.NextID, next.ID)
+	}
+
+	//reopening and completing the same step again must not add another occurrence
+	if again := save(data.StatusTodo); again != nil {
+		t.Errorf("reopening scheduled %v", again)
+	}
+	if again := save(data.StatusDone); again != nil {
+		t.Errorf("completing again scheduled a second occurrence %d", again.ID)
+	}
+
+	todos, _, err := store.GetAll(scope, data.TodoSearch{}, data.Filters{Page: 1, PageSize: 20, Sort: "id", SortList: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 {
+		t.Errorf("got %d todos, want the todo and one next occurrence", len(todos))
+	}
+}
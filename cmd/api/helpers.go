@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"todo.kegodo.net/internal/validator"
@@ -19,12 +20,17 @@ import (
 type envelope map[string]interface{}
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readInt64Param(r, "id")
+}
+
+// The readInt64Param() method reads a positive integer route parameter such as ":item_id"
+func (app *application) readInt64Param(r *http.Request, name string) (int64, error) {
 	// Use the "ParamsFromContext()" function to get the request context as a slice
 	params := httprouter.ParamsFromContext(r.Context())
-	// Get the value of the "id" parameter
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	// Get the value of the named parameter
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 	return id, nil
 }
@@ -145,3 +151,40 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	}
 	return intValue
 }
+
+// The readTime() method converts a string value from the query string to a time value.
+// Both RFC 3339 timestamps and plain dates (YYYY-MM-DD) are accepted. If the value
+// cannot be parsed then a validation error is added to the validation errors map
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	// Get the value
+	value := qs.Get(key)
+	if value == "" {
+		return nil
+	}
+	// Try each of the supported layouts in turn
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return &t
+		}
+	}
+	v.AddError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	return nil
+}
+
+// The readBool() method converts a string value from the query string to a boolean value.
+// If the value cannot be converted then a validation error is added to the validation errors map
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	// Get the value
+	value := qs.Get(key)
+	if value == "" {
+		return defaultValue
+	}
+	// Perform the conversion to a boolean
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return boolValue
+}
@@ -0,0 +1,156 @@
+// File: todo/cmd/api/history.go
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// The todoHistoryHandler lists every recorded change to a todo, oldest first
+func (app *application) todoHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetAllForTodo(todo.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"history": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The showTodoVersionHandler shows a todo as it was at an earlier version
+func (app *application) showTodoVersionHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
+		return
+	}
+	version, err := app.readInt64Param(r, "version")
+	if err != nil || version > math.MaxInt32 {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	snapshot, err := app.models.Revisions.GetAtVersion(todo.ID, int32(version))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	past := &data.Todo{
+		ID:          todo.ID,
+		OwnerID:     todo.OwnerID,
+		WorkspaceID: todo.WorkspaceID,
+		Version:     int32(version),
+	}
+	snapshot.ApplyTo(past)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"todo": past}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The revertTodoHandler puts the title, description and status of a todo back to
+// how they were at an earlier version. The revert is saved as a new version, so
+// the history keeps every step and the client's expected version is still honoured
+func (app *application) revertTodoHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchEditableTodo(w, r)
+	if !ok {
+		return
+	}
+	if !app.checkTodoVersion(w, r, todo) {
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Version > 0, "version", "must be provided")
+	v.Check(input.Version < todo.Version, "version", "must be earlier than the current version")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	snapshot, err := app.models.Revisions.GetAtVersion(todo.ID, input.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("version", "no history is recorded for this version")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	//Only these fields are reverted, the rest of the todo stays as it is now. The revert
+	//goes through the same status rules as an update
+	wasDone := todo.Status == data.StatusDone
+	revert := todoPatch{Title: &snapshot.Title, Description: &snapshot.Description, Status: &snapshot.Status}
+	revert.apply(v, todo)
+
+	if data.ValidateTodo(v, todo); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	//Reverting a recurring todo to done schedules its next occurrence like an update does
+	tx, err := app.models.Todos.Begin()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	next, err := saveTodo(tx, todo, app.contextGetScope(r), wasDone)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"todo": todo}
+	if next != nil {
+		env["next"] = next
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", todoETag(todo))
+	err = app.writeJSON(w, http.StatusOK, env, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
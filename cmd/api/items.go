@@ -0,0 +1,174 @@
+// File: todo/cmd/api/items.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// The listItemsHandler shows the checklist of a todo element
+func (app *application) listItemsHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := app.models.Items.GetAllForTodo(todo.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"items": items, "checklist": todo.Checklist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createItemHandler adds a checklist item to the end of a todo's checklist
+func (app *application) createItemHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchEditableTodo(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title string `json:"title"`
+		Done  bool   `json:"done"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	item := &data.Item{
+		TodoID: todo.ID,
+		Title:  input.Title,
+		Done:   input.Done,
+	}
+
+	v := validator.New()
+	if data.ValidateItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Items.Insert(item)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/todo/%d/items/%d", todo.ID, item.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"item": item}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The updateItemHandler edits, ticks off or moves a checklist item
+func (app *application) updateItemHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchEditableTodo(w, r)
+	if !ok {
+		return
+	}
+
+	itemID, err := app.readInt64Param(r, "item_id")
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	item, err := app.models.Items.Get(todo.ID, itemID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Title    *string `json:"title"`
+		Done     *bool   `json:"done"`
+		Position *int32  `json:"position"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		item.Title = *input.Title
+	}
+	if input.Done != nil {
+		item.Done = *input.Done
+	}
+	if input.Position != nil {
+		item.Position = *input.Position
+	}
+
+	v := validator.New()
+	if data.ValidateItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Items.Update(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The deleteItemHandler removes a checklist item from a todo
+func (app *application) deleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchEditableTodo(w, r)
+	if !ok {
+		return
+	}
+
+	itemID, err := app.readInt64Param(r, "item_id")
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	err = app.models.Items.Delete(todo.ID, itemID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "checklist item sucessfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
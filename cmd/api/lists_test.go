@@ -0,0 +1,72 @@
+// File: todo/cmd/api/lists_test.go
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"todo.kegodo.net/internal/data"
+)
+
+func TestListRoutes(t *testing.T) {
+	srv, _ := newPostgresTestServer(t)
+	alice := registerTestAccount(t, srv, "alice")
+	bob := registerTestAccount(t, srv, "bob")
+
+	created := send(t, srv, http.MethodPost, "/v1/lists", `{"name": "Groceries"}`, alice.auth...)
+	var list data.List
+	if err := decodeField(created, "list", &list); err != nil || created.status != http.StatusCreated {
+		t.Fatalf("create list answered %d %v", created.status, created.body)
+	}
+	path := fmt.Sprintf("/v1/lists/%d", list.ID)
+
+	todo := send(t, srv, http.MethodPost, "/v1/todo", fmt.Sprintf(`{"title": "Eggs", "list_id": %d}`, list.ID), alice.auth...)
+	if todo.status != http.StatusCreated {
+		t.Fatalf("create todo on the list answered %d %v", todo.status, todo.body)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "list lists", method: http.MethodGet, path: "/v1/lists", status: http.StatusOK},
+		{name: "show list", method: http.MethodGet, path: path, status: http.StatusOK},
+		{name: "rename list", method: http.MethodPatch, path: path, body: `{"name": "Food"}`, status: http.StatusOK},
+		{name: "list todos", method: http.MethodGet, path: path + "/todo", status: http.StatusOK},
+		{name: "share list", method: http.MethodPost, path: path + "/shares", body: fmt.Sprintf(`{"email": %q, "access": "view"}`, bob.email), status: http.StatusOK},
+		{name: "list shares", method: http.MethodGet, path: path + "/shares", status: http.StatusOK},
+		{name: "list tags", method: http.MethodGet, path: "/v1/tags", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := send(t, srv, tt.method, tt.path, tt.body, alice.auth...); resp.status != tt.status {
+				t.Errorf("%s %s answered %d %v, want %d", tt.method, tt.path, resp.status, resp.body, tt.status)
+			}
+		})
+	}
+
+	shown := send(t, srv, http.MethodGet, path, "", alice.auth...)
+	if err := decodeField(shown, "list", &list); err != nil || list.Name != "Food" || list.Todos != 1 {
+		t.Errorf("list is %+v, want Food with one todo", list)
+	}
+
+	//the list stays in alice's workspace, bob only sees his own
+	var bobs []data.List
+	if err := decodeField(send(t, srv, http.MethodGet, "/v1/lists", "", bob.auth...), "lists", &bobs); err != nil || len(bobs) != 0 {
+		t.Errorf("bob sees lists %+v in his workspace", bobs)
+	}
+	if resp := send(t, srv, http.MethodGet, path, "", bob.auth...); resp.status != http.StatusNotFound {
+		t.Errorf("bob showing alice's list answered %d, want 404", resp.status)
+	}
+
+	if resp := send(t, srv, http.MethodDelete, path, "", alice.auth...); resp.status != http.StatusOK {
+		t.Errorf("delete list answered %d %v", resp.status, resp.body)
+	}
+	if resp := send(t, srv, http.MethodGet, path, "", alice.auth...); resp.status != http.StatusNotFound {
+		t.Errorf("deleted list answered %d, want 404", resp.status)
+	}
+}
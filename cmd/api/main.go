@@ -4,13 +4,14 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 	"todo.kegodo.net/internal/data"
 )
 
@@ -20,10 +21,17 @@ type config struct {
 	env  string
 	db   struct { // development, staging, production, etc.
 		dsn          string
+		driver       string // postgres or sqlite, picked from the dsn
+		migrate      bool   // apply pending migrations at startup
 		maxOpenConns int
 		maxIdleConns int
 		MaxIdleTime  string
 	}
+	trash struct {
+		retention time.Duration // how long deleted todos can still be restored
+	}
+	demo            bool          // serve todos from memory without a database
+	shutdownTimeout time.Duration // how long to wait for requests and background tasks at shutdown
 }
 
 // The application version number
@@ -34,64 +42,136 @@ type application struct {
 	config config
 	logger *log.Logger
 	models data.Models
+	done   chan struct{}  // closed at shutdown to stop background tasks
+	wg     sync.WaitGroup // background tasks that shutdown waits for
 }
 
 // main
 func main() {
 	//initialize the config
 	var cfg config
-
-	// the settings that are needed to populate our config
-	cfg.port = 4000
-	cfg.env = "development"
-	cfg.db.dsn = os.Getenv("TODOS_DB_DSN")
-	cfg.db.maxOpenConns = 25
-	cfg.db.maxIdleConns = 25
-	cfg.db.MaxIdleTime = "15m"
+	var showConfig bool
 
 	//creating logger to log issues or state changes
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
-	//creating connection
-	db, err := openDB(cfg)
-	if err != nil {
+	//the settings are read from flags, the environment and an optional config file
+	flags := configFlags(&cfg)
+	flags.BoolVar(&showConfig, "print-config", false, "Print the effective config with secrets redacted and exit")
+	if err := loadConfig(flags, os.Args[1:]); err != nil {
+		logger.Fatal(err)
+	}
+	if showConfig {
+		if err := printConfig(os.Stdout, flags); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if err := validateConfig(cfg); err != nil {
 		logger.Fatal(err)
 	}
 
-	//ensuring that the connection to the database is closed
-	defer db.Close()
-
-	logger.Println("database connection pool established")
+	//`api migrate ...`, `api claim-todos ...` and `api grant-permission ...` do their job
+	//and exit instead of serving
+	switch flags.Arg(0) {
+	case "":
+	case "migrate":
+		if err := runMigrate(cfg, logger, flags.Args()[1:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	case "claim-todos":
+		if err := runClaimTodos(cfg, logger, flags.Args()[1:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	case "grant-permission":
+		if err := runGrantPermission(cfg, logger, flags.Args()[1:]); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	default:
+		logger.Fatalf("unknown command %q", flags.Arg(0))
+	}
 
 	//initializing the app struct
 	app := &application{
 		config: cfg,
 		logger: logger,
-		models: data.NewModels(db),
+		done:   make(chan struct{}),
 	}
 
-	//initializing http server dependencies
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.port),
-		Handler:      app.routes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+	var db *sql.DB
+	if cfg.demo {
+		app.models = data.Models{Todos: data.NewMemoryTodoStore()}
+		logger.Println("running in demo mode, todos are kept in memory")
+	} else {
+		//creating connection
+		var err error
+		db, err = openDB(&app.config)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Println("database connection pool established")
+
+		//the SQLite schema is always brought up to date, Postgres only when asked
+		if app.config.db.migrate || app.config.db.driver == "sqlite" {
+			m, err := newMigrator(db, app.config, logger)
+			if err == nil {
+				err = m.Up()
+			}
+			if err != nil {
+				logger.Fatal(err)
+			}
+		}
+
+		if app.config.db.driver == "sqlite" {
+			app.models = data.Models{Todos: data.SQLiteTodoStore{DB: db}}
+			logger.Println("using sqlite storage, only the todo endpoints are served")
+		} else {
+			app.models = data.NewModels(db)
+		}
 	}
 
-	//staring the web server
-	logger.Printf("starting %s server on %s", cfg.env, srv.Addr)
-	err = srv.ListenAndServe()
-	logger.Fatal(err)
+	//serving until a shutdown signal, the database is closed last
+	err := app.serve()
+	if db != nil {
+		logger.Println("closing database connection pool")
+		db.Close()
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Println("server stopped")
 }
 
-// OpenDB() function returns a *sql.DB connection pool
-func openDB(cfg config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.db.dsn)
+// The dbDriver() function picks the database driver from the scheme of a dsn. SQLite
+// dsns are sqlite://path, sqlite:path or a file: URI; anything else goes to Postgres
+func dbDriver(dsn string) (driver, name string) {
+	switch {
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return "sqlite", strings.TrimPrefix(dsn, "sqlite://")
+	case strings.HasPrefix(dsn, "sqlite:"):
+		return "sqlite", strings.TrimPrefix(dsn, "sqlite:")
+	case strings.HasPrefix(dsn, "file:"):
+		return "sqlite", dsn
+	}
+	return "postgres", dsn
+}
+
+// OpenDB() function returns a *sql.DB connection pool and records the driver it picked in cfg
+func openDB(cfg *config) (*sql.DB, error) {
+	driver, name := dbDriver(cfg.db.dsn)
+	cfg.db.driver = driver
+	db, err := sql.Open(driver, name)
 	if err != nil {
 		return nil, err
 	}
 	db.SetMaxOpenConns(cfg.db.maxOpenConns)
+	//SQLite allows a single writer, so one connection avoids busy errors
+	if driver == "sqlite" {
+		db.SetMaxOpenConns(1)
+	}
 	db.SetMaxIdleConns(cfg.db.maxIdleConns)
 	duration, err := time.ParseDuration(cfg.db.MaxIdleTime)
 	if err != nil {
@@ -0,0 +1,111 @@
+// File: todo/cmd/api/migrate.go
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"todo.kegodo.net/internal/migrate"
+	"todo.kegodo.net/migrations"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up          apply every pending migration
+  down [N]    revert the last N migrations (default 1)
+  goto V      migrate up or down to version V, 0 reverts everything
+  force V     mark version V as applied and clean after fixing a failed migration
+  status      show the applied version and pending migrations`
+
+// newMigrator() prepares the embedded migrations for the database in cfg
+func newMigrator(db *sql.DB, cfg config, logger *log.Logger) (*migrate.Migrator, error) {
+	files, err := migrations.Files(cfg.db.driver)
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.New(db, cfg.db.driver, files)
+	if err != nil {
+		return nil, err
+	}
+	m.Logger = logger
+	return m, nil
+}
+
+// runMigrate() carries out `api migrate <command>` against the configured database
+func runMigrate(cfg config, logger *log.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := openDB(&cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	//the commands taking a number
+	number := func(fallback uint) (uint, error) {
+		switch len(args) {
+		case 1:
+			return fallback, nil
+		case 2:
+			n, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %q is not a number", args[0], args[1])
+			}
+			return uint(n), nil
+		}
+		return 0, errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up()
+	case "down":
+		steps, err := number(1)
+		if err != nil {
+			return err
+		}
+		return m.Down(int(steps))
+	case "goto", "force":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := number(0)
+		if err != nil {
+			return err
+		}
+		if args[0] == "force" {
+			return m.Force(version)
+		}
+		return m.Goto(version)
+	case "status":
+		status, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, migration := range status.Migrations {
+			state := "pending"
+			if migration.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d  %-8s %s\n", migration.Version, state, migration.Name)
+		}
+		fmt.Printf("version %d", status.Version)
+		if status.Dirty {
+			fmt.Print(" (dirty)")
+		}
+		fmt.Println()
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,76 @@
+// File: todo/cmd/api/postgres_test.go
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"todo.kegodo.net/internal/data"
+)
+
+// newPostgresTestServer() serves the full set of routes from the database named by
+// TODOS_TEST_DSN, skipping the test when the variable is not set. Tests add rows with
+// unique names and never remove them, so the database should be a throwaway one
+func newPostgresTestServer(t *testing.T) (*httptest.Server, *application) {
+	t.Helper()
+	dsn := os.Getenv("TODOS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("TODOS_TEST_DSN is not set")
+	}
+
+	app := &application{
+		logger: log.New(io.Discard, "", 0),
+		done:   make(chan struct{}),
+	}
+	app.config.db.dsn = dsn
+	app.config.db.driver = "postgres"
+	app.config.db.MaxIdleTime = "15m"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := newMigrator(db, app.config, app.logger)
+	if err == nil {
+		err = m.Up()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.models = data.NewModels(db)
+
+	srv := httptest.NewServer(app.routes())
+	t.Cleanup(srv.Close)
+	return srv, app
+}
+
+// testAccount is a registered user and the headers that act as them
+type testAccount struct {
+	email string
+	auth  []string
+}
+
+// registerTestAccount() registers a user through the API and signs them in
+func registerTestAccount(t *testing.T, srv *httptest.Server, name string) testAccount {
+	t.Helper()
+	email := fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())
+	body := fmt.Sprintf(`{"name": %q, "email": %q, "password": "pa55word1234"}`, name, email)
+	if resp := send(t, srv, http.MethodPost, "/v1/users", body); resp.status != http.StatusCreated {
+		t.Fatalf("register answered %d %v", resp.status, resp.body)
+	}
+
+	resp := send(t, srv, http.MethodPost, "/v1/tokens/authentication", fmt.Sprintf(`{"email": %q, "password": "pa55word1234"}`, email))
+	var token data.Token
+	if err := decodeField(resp, "authentication_token", &token); err != nil || token.Plaintext == "" {
+		t.Fatalf("sign in answered %d %v", resp.status, resp.body)
+	}
+	return testAccount{email: email, auth: []string{"Authorization", "Bearer " + token.Plaintext}}
+}
@@ -5,21 +5,75 @@ import (
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	"todo.kegodo.net/internal/data"
 )
 
-func (app *application) routes() *httprouter.Router {
+func (app *application) routes() http.Handler {
+	if app.singleUser() {
+		return app.demoRoutes()
+	}
+
 	router := httprouter.New()
 
+	//shorter names for the permission codes used below
+	todosRead := data.PermissionTodosRead
+	todosWrite := data.PermissionTodosWrite
+	todosAdmin := data.PermissionTodosAdmin
+
 	//security routes
 	router.NotFound = http.HandlerFunc(app.notFoundReponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/todo", app.listTododHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/todo", app.createTodoHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/todo/:id", app.showTodoHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/todo/:id", app.updateTodoHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/todo/:id", app.deleteTodoHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/todo", app.requirePermission(todosRead, app.requireWorkspace(app.listTododHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/todo", app.requirePermission(todosWrite, app.requireWorkspace(app.createTodoHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/todo/:id", app.requirePermission(todosRead, app.requireWorkspace(app.showTodoHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/todo/:id", app.requirePermission(todosWrite, app.requireWorkspace(app.updateTodoHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/todo/:id", app.requirePermission(todosWrite, app.requireWorkspace(app.deleteTodoHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id", app.postTodoHandler(app.requirePermission(todosWrite, app.requireWorkspace(app.bulkTodoHandler))))
+	router.HandlerFunc(http.MethodGet, "/v1/todo/:id/history", app.requirePermission(todosRead, app.requireWorkspace(app.todoHistoryHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/todo/:id/history/:version", app.requirePermission(todosRead, app.requireWorkspace(app.showTodoVersionHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id/revert", app.requirePermission(todosWrite, app.requireWorkspace(app.revertTodoHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id/restore", app.requirePermission(todosWrite, app.requireWorkspace(app.restoreTodoHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/trash", app.requirePermission(todosRead, app.requireWorkspace(app.listTrashHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/trash/:id", app.requirePermission(todosWrite, app.requireWorkspace(app.purgeTodoHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/todo/:id/items", app.requirePermission(todosRead, app.requireWorkspace(app.listItemsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id/items", app.requirePermission(todosWrite, app.requireWorkspace(app.createItemHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/todo/:id/items/:item_id", app.requirePermission(todosWrite, app.requireWorkspace(app.updateItemHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/todo/:id/items/:item_id", app.requirePermission(todosWrite, app.requireWorkspace(app.deleteItemHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/todo/:id/shares", app.requirePermission(todosRead, app.requireWorkspace(app.listTodoSharesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/todo/:id/shares", app.requirePermission(todosWrite, app.requireWorkspace(app.grantTodoShareHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/todo/:id/shares/:user_id", app.requirePermission(todosWrite, app.requireWorkspace(app.revokeTodoShareHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/lists", app.requirePermission(todosRead, app.requireWorkspace(app.listListsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/lists", app.requirePermission(todosWrite, app.requireWorkspace(app.createListHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id", app.requirePermission(todosRead, app.requireWorkspace(app.showListHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id", app.requirePermission(todosWrite, app.requireWorkspace(app.updateListHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id", app.requirePermission(todosWrite, app.requireWorkspace(app.deleteListHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id/todo", app.requirePermission(todosRead, app.requireWorkspace(app.listListTodosHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id/shares", app.requirePermission(todosRead, app.requireWorkspace(app.listListSharesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/lists/:id/shares", app.requirePermission(todosWrite, app.requireWorkspace(app.grantListShareHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/shares/:user_id", app.requirePermission(todosWrite, app.requireWorkspace(app.revokeListShareHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.requirePermission(todosRead, app.requireWorkspace(app.listTagsHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/tags/:id", app.requirePermission(todosAdmin, app.requireWorkspace(app.renameTagHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/tags/:id/merge", app.requirePermission(todosAdmin, app.requireWorkspace(app.mergeTagHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/workspaces", app.requireAuthenticatedUser(app.listWorkspacesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/workspaces", app.requireAuthenticatedUser(app.createWorkspaceHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/workspaces/:id/members", app.requireAuthenticatedUser(app.listMembersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/workspaces/:id/members", app.requireAuthenticatedUser(app.addMemberHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/workspaces/:id/members/:user_id", app.requireAuthenticatedUser(app.removeMemberHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/api-keys", app.requireSessionUser(app.listAPIKeysHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/api-keys", app.requireSessionUser(app.createAPIKeyHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/api-keys/:id", app.requireSessionUser(app.updateAPIKeyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/api-keys/:id", app.requireSessionUser(app.revokeAPIKeyHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
 
-	return router
+	return app.authenticate(router)
 }
@@ -0,0 +1,84 @@
+// File: todo/cmd/api/server.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve() runs the web server until SIGINT or SIGTERM, then stops taking requests,
+// lets the ones in flight finish and waits for the background tasks, all within
+// the configured shutdown timeout
+func (app *application) serve() error {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.port),
+		Handler:      app.routes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	shutdownError := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Printf("caught %s, shutting down server", s)
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdownTimeout)
+		defer cancel()
+
+		//Shutdown() returns once every in-flight request has completed
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+		app.logger.Println("in-flight requests completed, stopping background tasks")
+
+		close(app.done)
+		stopped := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			app.logger.Println("background tasks completed")
+			shutdownError <- nil
+		case <-ctx.Done():
+			shutdownError <- errors.New("timed out waiting for background tasks")
+		}
+	}()
+
+	//emptying the trash of expired todos in the background
+	app.background(app.purgeTrash)
+
+	app.logger.Printf("starting %s server on %s", app.config.env, srv.Addr)
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-shutdownError
+}
+
+// background() runs fn in a goroutine that shutdown waits for. fn should return
+// once app.done is closed. A panic is logged rather than taking the server down
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Printf("background task panicked: %v", err)
+			}
+		}()
+		fn()
+	}()
+}
@@ -0,0 +1,183 @@
+// File: todo/cmd/api/shares.go
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// The listTodoSharesHandler shows who a todo has been shared with
+func (app *application) listTodoSharesHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchOwnedTodo(w, r)
+	if !ok {
+		return
+	}
+	app.listShares(w, r, data.ShareTodo, todo.ID)
+}
+
+// The grantTodoShareHandler gives another user view or edit rights on a todo
+func (app *application) grantTodoShareHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchOwnedTodo(w, r)
+	if !ok {
+		return
+	}
+	app.grantShare(w, r, data.ShareTodo, todo.ID)
+}
+
+// The revokeTodoShareHandler takes back the rights another user holds on a todo
+func (app *application) revokeTodoShareHandler(w http.ResponseWriter, r *http.Request) {
+	todo, ok := app.fetchOwnedTodo(w, r)
+	if !ok {
+		return
+	}
+	app.revokeShare(w, r, data.ShareTodo, todo.ID)
+}
+
+// The listListSharesHandler shows who a list has been shared with
+func (app *application) listListSharesHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.fetchOwnedList(w, r)
+	if !ok {
+		return
+	}
+	app.listShares(w, r, data.ShareList, list.ID)
+}
+
+// The grantListShareHandler gives another user view or edit rights on a list and its todos
+func (app *application) grantListShareHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.fetchOwnedList(w, r)
+	if !ok {
+		return
+	}
+	app.grantShare(w, r, data.ShareList, list.ID)
+}
+
+// The revokeListShareHandler takes back the rights another user holds on a list
+func (app *application) revokeListShareHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.fetchOwnedList(w, r)
+	if !ok {
+		return
+	}
+	app.revokeShare(w, r, data.ShareList, list.ID)
+}
+
+func (app *application) listShares(w http.ResponseWriter, r *http.Request, kind data.ShareKind, resourceID int64) {
+	shares, err := app.models.Shares.GetAll(kind, resourceID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shares": shares}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) grantShare(w http.ResponseWriter, r *http.Request, kind data.ShareKind, resourceID int64) {
+	var input struct {
+		Email  string      `json:"email"`
+		Access data.Access `json:"access"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	data.ValidateShareAccess(v, input.Access)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no user with this email address exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if v.Check(user.ID != app.contextGetUser(r).ID, "email", "you already own this resource"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	share := &data.Share{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Access: input.Access,
+	}
+
+	err = app.models.Shares.Grant(kind, resourceID, share)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"share": share}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) revokeShare(w http.ResponseWriter, r *http.Request, kind data.ShareKind, resourceID int64) {
+	userID, err := app.readInt64Param(r, "user_id")
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	err = app.models.Shares.Revoke(kind, resourceID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "share sucessfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// fetchOwnedTodo() works like fetchTodo() but only lets the owner through
+func (app *application) fetchOwnedTodo(w http.ResponseWriter, r *http.Request) (*data.Todo, bool) {
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
+		return nil, false
+	}
+	if todo.Access != data.AccessOwner {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+	return todo, true
+}
+
+// fetchOwnedList() works like fetchList() but only lets the owner through
+func (app *application) fetchOwnedList(w http.ResponseWriter, r *http.Request) (*data.List, bool) {
+	list, ok := app.fetchList(w, r)
+	if !ok {
+		return nil, false
+	}
+	if list.Access != data.AccessOwner {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+	return list, true
+}
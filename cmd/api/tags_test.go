@@ -0,0 +1,69 @@
+// File: todo/cmd/api/tags_test.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+
+	"todo.kegodo.net/internal/data"
+)
+
+func TestGrantPermissionRejectsUnknownCodes(t *testing.T) {
+	var cfg config
+	err := runGrantPermission(cfg, log.New(io.Discard, "", 0), []string{"alice@example.com", "todos:everything"})
+	if err == nil {
+		t.Fatal("granting an unknown permission succeeded")
+	}
+	if err := runGrantPermission(cfg, log.New(io.Discard, "", 0), []string{"alice@example.com"}); err == nil {
+		t.Fatal("granting without a permission code succeeded")
+	}
+}
+
+func TestRenameAndMergeTags(t *testing.T) {
+	srv, app := newPostgresTestServer(t)
+	alice := registerTestAccount(t, srv, "alice")
+
+	for _, body := range []string{`{"title": "Fix the tap", "tags": ["home"]}`, `{"title": "Paint the door", "tags": ["house"]}`} {
+		if resp := send(t, srv, http.MethodPost, "/v1/todo", body, alice.auth...); resp.status != http.StatusCreated {
+			t.Fatalf("create todo answered %d %v", resp.status, resp.body)
+		}
+	}
+	tags := map[string]int64{}
+	var list []data.Tag
+	if err := decodeField(send(t, srv, http.MethodGet, "/v1/tags", "", alice.auth...), "tags", &list); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range list {
+		tags[tag.Name] = tag.ID
+	}
+	home := fmt.Sprintf("/v1/tags/%d", tags["home"])
+
+	//registration does not make anyone an admin
+	if resp := send(t, srv, http.MethodPatch, home, `{"name": "household"}`, alice.auth...); resp.status != http.StatusForbidden {
+		t.Fatalf("rename without todos:admin answered %d, want 403", resp.status)
+	}
+
+	if err := runGrantPermission(app.config, app.logger, []string{alice.email, data.PermissionTodosAdmin}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tag data.Tag
+	renamed := send(t, srv, http.MethodPatch, home, `{"name": "Household"}`, alice.auth...)
+	if err := decodeField(renamed, "tag", &tag); err != nil || renamed.status != http.StatusOK || tag.Name != "household" {
+		t.Fatalf("rename answered %d %v", renamed.status, renamed.body)
+	}
+
+	merged := send(t, srv, http.MethodPost, home+"/merge", fmt.Sprintf(`{"into": %d}`, tags["house"]), alice.auth...)
+	if err := decodeField(merged, "tag", &tag); err != nil || merged.status != http.StatusOK {
+		t.Fatalf("merge answered %d %v", merged.status, merged.body)
+	}
+	if tag.ID != tags["house"] || tag.Todos != 2 {
+		t.Errorf("merged tag is %+v, want house on both todos", tag)
+	}
+	if resp := send(t, srv, http.MethodPatch, home, `{"name": "gone"}`, alice.auth...); resp.status != http.StatusNotFound {
+		t.Errorf("renaming the merged tag answered %d, want 404", resp.status)
+	}
+}
@@ -0,0 +1,118 @@
+// File: todo/cmd/api/trash.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// The listTrashHandler shows a page of the todos the user has deleted
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var filters data.Filters
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "-deleted_at")
+	filters.SortList = []string{"deleted_at", "title", "-deleted_at", "-title"}
+
+	if data.ValidateFilter(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tasks, metadata, err := app.models.Todos.GetTrash(app.contextGetScope(r), filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"todos": tasks, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The restoreTodoHandler takes a todo back out of the trash
+func (app *application) restoreTodoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	err = app.models.Todos.Restore(id, app.contextGetScope(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	todo, ok := app.fetchTodo(w, r)
+	if !ok {
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", todoETag(todo))
+	err = app.writeJSON(w, http.StatusOK, envelope{"todo": todo}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The purgeTodoHandler permanently deletes a todo that is already in the trash
+func (app *application) purgeTodoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	err = app.models.Todos.Purge(id, app.contextGetScope(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "todo element permanently deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// purgeTrash() runs in the background and permanently deletes todos that have
+// been in the trash for longer than the configured retention period, until shutdown
+func (app *application) purgeTrash() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		purged, err := app.models.Todos.PurgeExpired(app.config.trash.retention)
+		if err != nil {
+			app.logger.Printf("purging trash: %v", err)
+		} else if purged > 0 {
+			app.logger.Printf("purged %d todos from the trash", purged)
+		}
+		select {
+		case <-ticker.C:
+		case <-app.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// File: todo/cmd/api/workspaces.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"todo.kegodo.net/internal/data"
+	"todo.kegodo.net/internal/validator"
+)
+
+// The listWorkspacesHandler shows the workspaces the user is a member of
+func (app *application) listWorkspacesHandler(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := app.models.Workspaces.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"workspaces": workspaces}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createWorkspaceHandler creates a workspace owned by the user
+func (app *application) createWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	workspace := &data.Workspace{Name: input.Name}
+
+	v := validator.New()
+	if data.ValidateWorkspace(v, workspace); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Workspaces.Insert(workspace, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/workspaces/%d", workspace.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"workspace": workspace}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The listMembersHandler shows the members of a workspace to any of its members
+func (app *application) listMembersHandler(w http.ResponseWriter, r *http.Request) {
+	workspace, ok := app.fetchWorkspace(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := app.models.Workspaces.GetMembers(workspace.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"members": members}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The addMemberHandler lets a workspace owner add a user or change their role
+func (app *application) addMemberHandler(w http.ResponseWriter, r *http.Request) {
+	workspace, ok := app.fetchWorkspace(w, r)
+	if !ok {
+		return
+	}
+	if workspace.Role != data.RoleOwner {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Role == "" {
+		input.Role = data.RoleMember
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	data.ValidateRole(v, input.Role)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no user with this email address exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	member := &data.Member{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Role:   input.Role,
+	}
+
+	err = app.models.Workspaces.AddMember(workspace.ID, member)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"member": member}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The removeMemberHandler lets a workspace owner remove a member. Members may remove themselves
+func (app *application) removeMemberHandler(w http.ResponseWriter, r *http.Request) {
+	workspace, ok := app.fetchWorkspace(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := app.readInt64Param(r, "user_id")
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return
+	}
+
+	if workspace.Role != data.RoleOwner && userID != app.contextGetUser(r).ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Workspaces.RemoveMember(workspace.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "member sucessfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// fetchWorkspace() loads the workspace named by the ":id" route parameter and writes
+// the error response itself when the user is not a member of it
+func (app *application) fetchWorkspace(w http.ResponseWriter, r *http.Request) (*data.Workspace, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundReponse(w, r)
+		return nil, false
+	}
+
+	workspace, err := app.models.Workspaces.GetForUser(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundReponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return workspace, true
+}
@@ -0,0 +1,280 @@
+// File: todo/internal/data/apikeys.go
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"todo.kegodo.net/internal/validator"
+)
+
+// APIKeyPrefix marks a bearer token as a long-lived API key rather than a session token
+const APIKeyPrefix = "todo_"
+
+// APIKey struct supports the information for a long-lived key used by scripts and CI.
+// Only the hash of the key is stored; the plaintext is shown once when the key is created
+type APIKey struct {
+	ID         int64       `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UserID     int64       `json:"-"`
+	Name       string      `json:"name"`
+	Plaintext  string      `json:"key,omitempty"`
+	Hash       []byte      `json:"-"`
+	Hint       string      `json:"hint"`
+	Scopes     Permissions `json:"scopes"`
+	Expiry     *time.Time  `json:"expiry,omitempty"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	Version    int32       `json:"version"`
+}
+
+// IsAPIKey() reports whether a bearer token looks like an API key
+func IsAPIKey(tokenPlaintext string) bool {
+	return strings.HasPrefix(tokenPlaintext, APIKeyPrefix)
+}
+
+// generateAPIKey() fills in a new random key, its hash and a short hint for recognising it
+func generateAPIKey(key *APIKey) error {
+	randomBytes := make([]byte, 20)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	key.Plaintext = APIKeyPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))
+	hash := sha256.Sum256([]byte(key.Plaintext))
+	key.Hash = hash[:]
+	key.Hint = key.Plaintext[len(key.Plaintext)-4:]
+	return nil
+}
+
+func ValidateAPIKey(v *validator.Validator, key *APIKey) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Scopes) > 0, "scopes", "must contain at least one permission")
+	v.Check(validator.Unique(key.Scopes), "scopes", "must not contain duplicate values")
+	for _, scope := range key.Scopes {
+		v.Check(validator.In(scope, PermissionTodosRead, PermissionTodosWrite, PermissionTodosAdmin), "scopes", "must only contain todos:read, todos:write or todos:admin")
+	}
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// New() generates a key for the user and stores its hash
+func (m APIKeyModel) New(key *APIKey) error {
+	err := generateAPIKey(key)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, hash, hint, scopes, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, createdat, version
+	`
+
+	args := []interface{}{key.UserID, key.Name, key.Hash, key.Hint, pq.Array([]string(key.Scopes)), key.Expiry}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt, &key.Version)
+}
+
+// Get() retrieves one of the user's keys
+func (m APIKeyModel) Get(id int64, userID int64) (*APIKey, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, createdat, user_id, name, hint, scopes, expiry, lastusedat, revokedat, version
+		FROM api_keys
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var key APIKey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&key.ID,
+		&key.CreatedAt,
+		&key.UserID,
+		&key.Name,
+		&key.Hint,
+		pq.Array((*[]string)(&key.Scopes)),
+		&key.Expiry,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &key, nil
+}
+
+// GetAllForUser() lists every key the user has created, newest first
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT id, createdat, user_id, name, hint, scopes, expiry, lastusedat, revokedat, version
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.CreatedAt,
+			&key.UserID,
+			&key.Name,
+			&key.Hint,
+			pq.Array((*[]string)(&key.Scopes)),
+			&key.Expiry,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+			&key.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Update() renames a key or changes its scopes
+func (m APIKeyModel) Update(key *APIKey) error {
+	query := `
+		UPDATE api_keys
+		SET name = $1, scopes = $2, version = version + 1
+		WHERE id = $3 AND user_id = $4 AND revokedat IS NULL
+		RETURNING version
+	`
+
+	args := []interface{}{key.Name, pq.Array([]string(key.Scopes)), key.ID, key.UserID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&key.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Revoke() stops a key from working. The key is kept so it still shows up in the listing
+func (m APIKeyModel) Revoke(id int64, userID int64) error {
+	query := `
+		UPDATE api_keys
+		SET revokedat = NOW(), version = version + 1
+		WHERE id = $1 AND user_id = $2 AND revokedat IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Authenticate() looks up a live key by its plaintext, records that it has been
+// used and returns it along with its owner
+func (m APIKeyModel) Authenticate(keyPlaintext string) (*APIKey, *User, error) {
+	keyHash := sha256.Sum256([]byte(keyPlaintext))
+
+	query := `
+		UPDATE api_keys
+		SET lastusedat = NOW()
+		FROM users
+		WHERE users.id = api_keys.user_id
+		AND api_keys.hash = $1
+		AND api_keys.revokedat IS NULL
+		AND (api_keys.expiry IS NULL OR api_keys.expiry > NOW())
+		RETURNING api_keys.id, api_keys.createdat, api_keys.name, api_keys.hint, api_keys.scopes,
+		          api_keys.expiry, api_keys.lastusedat, api_keys.version,
+		          users.id, users.createdat, users.name, users.email, users.password_hash, users.version
+	`
+
+	var key APIKey
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, keyHash[:]).Scan(
+		&key.ID,
+		&key.CreatedAt,
+		&key.Name,
+		&key.Hint,
+		pq.Array((*[]string)(&key.Scopes)),
+		&key.Expiry,
+		&key.LastUsedAt,
+		&key.Version,
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+	key.UserID = user.ID
+	return &key, &user, nil
+}
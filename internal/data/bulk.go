@@ -0,0 +1,95 @@
+// File: todo/internal/data/bulk.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TodoBatch runs a batch of todo writes as a single unit. Each step can be wrapped
+// in a savepoint so that one failing item does not abort the whole batch
+type TodoBatch interface {
+	Insert(todo *Todo) error
+	Get(id int64, scope Scope) (*Todo, error)
+	Update(todo *Todo, scope Scope) error
+	Delete(id int64, version int32, scope Scope) error
+	Savepoint() error
+	ReleaseSavepoint() error
+	RollbackToSavepoint() error
+	Commit() error
+	Rollback() error
+}
+
+// TodoTx is the TodoBatch of TodoModel, backed by a database transaction
+type TodoTx struct {
+	tx     *sql.Tx
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Begin() starts a batch. The whole batch shares one deadline, which is longer than
+// the single query timeout because it may carry hundreds of statements
+func (m TodoModel) Begin() (TodoBatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &TodoTx{tx: tx, ctx: ctx, cancel: cancel}, nil
+}
+
+// Insert() creates a todo as part of the batch
+func (t *TodoTx) Insert(todo *Todo) error {
+	return insertTodo(t.ctx, t.tx, todo)
+}
+
+// Get() reads a todo as part of the batch, seeing the batch's earlier writes
+func (t *TodoTx) Get(id int64, scope Scope) (*Todo, error) {
+	return getTodo(t.ctx, t.tx, id, scope)
+}
+
+// Update() edits a todo as part of the batch
+func (t *TodoTx) Update(todo *Todo, scope Scope) error {
+	return updateTodo(t.ctx, t.tx, todo, scope)
+}
+
+// Delete() removes a todo as part of the batch
+func (t *TodoTx) Delete(id int64, version int32, scope Scope) error {
+	return deleteTodo(t.ctx, t.tx, id, version, scope)
+}
+
+// Savepoint() marks the start of a step that may later be undone on its own
+func (t *TodoTx) Savepoint() error {
+	_, err := t.tx.ExecContext(t.ctx, `SAVEPOINT bulk_item`)
+	return err
+}
+
+// ReleaseSavepoint() keeps the writes made since the last Savepoint()
+func (t *TodoTx) ReleaseSavepoint() error {
+	_, err := t.tx.ExecContext(t.ctx, `RELEASE SAVEPOINT bulk_item`)
+	return err
+}
+
+// RollbackToSavepoint() undoes the writes made since the last Savepoint()
+func (t *TodoTx) RollbackToSavepoint() error {
+	_, err := t.tx.ExecContext(t.ctx, `ROLLBACK TO SAVEPOINT bulk_item`)
+	return err
+}
+
+// Commit() makes every write in the batch visible
+func (t *TodoTx) Commit() error {
+	defer t.cancel()
+	return t.tx.Commit()
+}
+
+// Rollback() abandons the batch. It is safe to call after Commit()
+func (t *TodoTx) Rollback() error {
+	defer t.cancel()
+	err := t.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
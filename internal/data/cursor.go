@@ -0,0 +1,95 @@
+// File: todo/internal/data/cursor.go
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that does not decode or fit the sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor marks a position in a sorted listing. Values holds the row's value for
+// each sort key (id last) so the next page can carry on from it even when rows
+// have been inserted or removed in the meantime. Clients only see it encoded
+type cursor struct {
+	Sort   string    `json:"s"`
+	Before bool      `json:"b,omitempty"`
+	Values []*string `json:"v"`
+}
+
+// Postgres types of the todo sort columns, used to cast the cursor values
+var cursorTypes = map[string]string{
+	"id":          "bigint",
+	"title":       "text",
+	"description": "text",
+	"status":      "text",
+	"priority":    "smallint",
+	"dueat":       "timestamptz",
+	"startsat":    "timestamptz",
+}
+
+// The cursorValueValid() function reports whether a cursor value parses as the
+// type of its sort column. A nil value stands for NULL and always fits
+func cursorValueValid(column string, value *string) bool {
+	if value == nil {
+		return true
+	}
+	var err error
+	switch cursorTypes[column] {
+	case "bigint":
+		_, err = strconv.ParseInt(*value, 10, 64)
+	case "smallint":
+		_, err = strconv.ParseInt(*value, 10, 16)
+	case "timestamptz":
+		_, err = time.Parse(time.RFC3339Nano, *value)
+	}
+	return err == nil
+}
+
+// The encodeCursor() function turns a cursor into the opaque string handed to clients
+func encodeCursor(c cursor) string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// The decodeCursor() function reverses encodeCursor()
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(js, &c)
+	return c, err
+}
+
+// The keysetCondition() function builds the WHERE clause picking the rows that come
+// after the cursor position in the given order. placeholders holds the parameter
+// for each key's cursor value. For keys k1, k2 it reads: k1 after c1 OR (k1 = c1 AND k2 after c2)
+func keysetCondition(keys []sortKey, reverse bool, placeholders []string) string {
+	condition := ""
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		value := placeholders[i]
+		op := ">"
+		if key.desc != reverse {
+			op = "<"
+		}
+		//NULLs sort last going forwards and first going backwards
+		after := fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s IS NULL AND %[3]s IS NOT NULL))", key.column, op, value)
+		if reverse {
+			after = fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s IS NOT NULL AND %[3]s IS NULL))", key.column, op, value)
+		}
+		if condition == "" {
+			condition = after
+		} else {
+			condition = fmt.Sprintf("(%s OR (%s IS NOT DISTINCT FROM %s AND %s))", after, key.column, value, condition)
+		}
+	}
+	return condition
+}
@@ -2,6 +2,7 @@
 package data
 
 import (
+	"fmt"
 	"math"
 	"strings"
 
@@ -13,24 +14,68 @@ type Filters struct {
 	PageSize int
 	Sort     string
 	SortList []string
+	//Cursor paging replaces page numbers with an opaque position. PageSize
+	//then holds the limit and Cursor is empty for the first page
+	UseCursor bool
+	Cursor    string
 }
 
 func ValidateFilter(v *validator.Validator, f Filters) {
-	//checking page and page_size parameters
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 1000, "page", "maximum of 1000")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "maximum of 100")
+	if f.UseCursor {
+		//checking the limit and the cursor
+		v.Check(f.PageSize > 0, "limit", "must be greater than zero")
+		v.Check(f.PageSize <= 100, "limit", "maximum of 100")
+		//the cursor must hold one value of the right type per sort key. The keys
+		//are only known once the sort itself has passed the safelist
+		if f.Cursor != "" && validator.In(f.Sort, f.SortList...) {
+			v.Check(f.cursorValid(), "cursor", "invalid cursor for this sort")
+		}
+	} else {
+		//checking page and page_size parameters
+		v.Check(f.Page > 0, "page", "must be greater than zero")
+		v.Check(f.Page <= 1000, "page", "maximum of 1000")
+		v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+		v.Check(f.PageSize <= 100, "page_size", "maximum of 100")
+	}
 
 	//checking that the sort parameter matches the value in the acceptable sort list
 	v.Check(validator.In(f.Sort, f.SortList...), "sort", "invalid sort value")
 }
 
+// The cursorValid() method checks that the cursor decodes and fits the sort keys
+func (f Filters) cursorValid() bool {
+	c, err := decodeCursor(f.Cursor)
+	if err != nil || c.Sort != f.Sort {
+		return false
+	}
+	keys := f.sortKeys()
+	if len(c.Values) != len(keys) {
+		return false
+	}
+	for i, key := range keys {
+		if !cursorValueValid(key.column, c.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sort values that do not match their column name in the database
+var sortColumns = map[string]string{
+	"due_at":     "dueat",
+	"starts_at":  "startsat",
+	"deleted_at": "deletedat",
+}
+
 // The sortColumn() method safely extracts the sort field query parameter
 func (f Filters) sortColumn() string {
 	for _, safeValue := range f.SortList {
 		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+			column := strings.TrimPrefix(f.Sort, "-")
+			if name, ok := sortColumns[column]; ok {
+				return name
+			}
+			return column
 		}
 	}
 	panic("unsafe sort parameter: " + f.Sort)
@@ -44,6 +89,51 @@ func (f Filters) sortOrder() string {
 	return "ASC"
 }
 
+// sortKey is one column of the ORDER BY list
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// Extra ordering applied after the chosen sort column, before the final id tie-breaker
+var sortTieBreakers = map[string][]sortKey{
+	"priority": {{column: "dueat"}},
+}
+
+// The sortKeys() method lists the columns results are ordered by. id always comes
+// last so that every row has a unique position
+func (f Filters) sortKeys() []sortKey {
+	column := f.sortColumn()
+	keys := []sortKey{{column: column, desc: f.sortOrder() == "DESC"}}
+	if column == "id" {
+		return keys
+	}
+	keys = append(keys, sortTieBreakers[column]...)
+	return append(keys, sortKey{column: "id"})
+}
+
+// The orderBy() method builds the ORDER BY list for the chosen sort
+func (f Filters) orderBy() string {
+	return orderBy(f.sortKeys(), false)
+}
+
+// The orderBy() function joins sort keys into an ORDER BY list. NULLs always sort
+// last, so a reversed list (used to page backwards) puts them first
+func orderBy(keys []sortKey, reverse bool) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		order, nulls := "ASC", "NULLS LAST"
+		if key.desc != reverse {
+			order = "DESC"
+		}
+		if reverse {
+			nulls = "NULLS FIRST"
+		}
+		parts[i] = fmt.Sprintf("%s %s %s", key.column, order, nulls)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // The limit() method detemerins the LIMIT
 func (f Filters) limit() int {
 	return f.PageSize
@@ -61,6 +151,9 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+	//only set when paging with cursors
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // The calculateMetaData() function computes the values for the metadata fields
@@ -0,0 +1,192 @@
+// File: todo/internal/data/items.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"todo.kegodo.net/internal/validator"
+)
+
+// Item struct supports the information for a checklist item under a todo
+type Item struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	TodoID    int64     `json:"todo_id"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	Position  int32     `json:"position"`
+	Version   int32     `json:"version"`
+}
+
+// Checklist rolls the items of a todo up into a single summary
+type Checklist struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func ValidateItem(v *validator.Validator, item *Item) {
+	v.Check(item.Title != "", "title", "must be provided")
+	v.Check(len(item.Title) <= 250, "title", "must not be more than 250 bytes long")
+	v.Check(item.Position >= 0, "position", "must not be negative")
+}
+
+type ItemModel struct {
+	DB *sql.DB
+}
+
+// Insert() adds a checklist item to the end of a todo's checklist
+func (m ItemModel) Insert(item *Item) error {
+	query := `
+		INSERT INTO todo_items (todo_id, title, done, position)
+		VALUES ($1, $2, $3, (SELECT COALESCE(MAX(position), 0) + 1 FROM todo_items WHERE todo_id = $1))
+		RETURNING id, createdat, position, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{item.TodoID, item.Title, item.Done}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Position, &item.Version)
+}
+
+// Get() retrieves a specific checklist item belonging to a todo
+func (m ItemModel) Get(todoID, id int64) (*Item, error) {
+	if todoID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, createdat, todo_id, title, done, position, version
+		FROM todo_items
+		WHERE id = $1 AND todo_id = $2
+	`
+
+	var item Item
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, todoID).Scan(
+		&item.ID,
+		&item.CreatedAt,
+		&item.TodoID,
+		&item.Title,
+		&item.Done,
+		&item.Position,
+		&item.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &item, nil
+}
+
+// GetAllForTodo() returns the checklist of a todo in position order
+func (m ItemModel) GetAllForTodo(todoID int64) ([]*Item, error) {
+	query := `
+		SELECT id, createdat, todo_id, title, done, position, version
+		FROM todo_items
+		WHERE todo_id = $1
+		ORDER BY position ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*Item{}
+	for rows.Next() {
+		var item Item
+		err := rows.Scan(
+			&item.ID,
+			&item.CreatedAt,
+			&item.TodoID,
+			&item.Title,
+			&item.Done,
+			&item.Position,
+			&item.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Update() edits a checklist item
+func (m ItemModel) Update(item *Item) error {
+	query := `
+		UPDATE todo_items
+		SET title = $1, done = $2, position = $3, version = version + 1
+		WHERE id = $4 AND todo_id = $5
+		RETURNING version
+	`
+	args := []interface{}{
+		item.Title,
+		item.Done,
+		item.Position,
+		item.ID,
+		item.TodoID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete() removes a checklist item from a todo
+func (m ItemModel) Delete(todoID, id int64) error {
+	if todoID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM todo_items
+		WHERE id = $1 AND todo_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, todoID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// File: todo/internal/data/lists.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"todo.kegodo.net/internal/validator"
+)
+
+var ErrListNotFound = errors.New("list not found")
+
+// List struct supports the information for a named list (project) of todos
+type List struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Todos       int       `json:"todos"`
+	OwnerID     int64     `json:"owner_id"`
+	WorkspaceID int64     `json:"workspace_id,omitempty"`
+	Access      Access    `json:"access"`
+	Version     int32     `json:"version"`
+}
+
+func ValidateList(v *validator.Validator, list *List) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(len(list.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(list.Description) <= 250, "description", "must not be more than 250 bytes long")
+}
+
+type ListModel struct {
+	DB *sql.DB
+}
+
+// Insert() creates a new list owned by list.OwnerID in list.WorkspaceID
+func (m ListModel) Insert(list *List) error {
+	query := `
+		INSERT INTO lists (name, description, owner_id, workspace_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, createdat, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, list.Name, list.Description, list.OwnerID, list.WorkspaceID).Scan(&list.ID, &list.CreatedAt, &list.Version)
+	if err != nil {
+		return err
+	}
+	list.Access = AccessOwner
+	return nil
+}
+
+// Get() retrieves a specific list of the scope's workspace the user is allowed to see
+func (m ListModel) Get(id int64, scope Scope) (*List, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, createdat, name, description,
+		       (SELECT COUNT(*) FROM todos
+		        WHERE todos.list_id = lists.id AND todos.workspace_id = lists.workspace_id AND todos.deletedat IS NULL),
+		       COALESCE(owner_id, 0), workspace_id, %s,
+		       version
+		FROM lists
+		WHERE id = $1
+		AND workspace_id = $3
+		AND %s`, accessColumn("lists", "$2", listEditAccess), fmt.Sprintf(listViewAccess, "$2"))
+
+	var list List
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, scope.UserID, scope.WorkspaceID).Scan(
+		&list.ID,
+		&list.CreatedAt,
+		&list.Name,
+		&list.Description,
+		&list.Todos,
+		&list.OwnerID,
+		&list.WorkspaceID,
+		&list.Access,
+		&list.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &list, nil
+}
+
+// GetAll() returns a page of the lists of the scope's workspace the user is allowed to see
+func (m ListModel) GetAll(scope Scope, filters Filters) ([]*List, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, createdat, name, description,
+		       (SELECT COUNT(*) FROM todos
+		        WHERE todos.list_id = lists.id AND todos.workspace_id = lists.workspace_id AND todos.deletedat IS NULL),
+		       COALESCE(owner_id, 0), workspace_id, %s,
+		       version
+		FROM lists
+		WHERE workspace_id = $4
+		AND %s
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`,
+		accessColumn("lists", "$1", listEditAccess), fmt.Sprintf(listViewAccess, "$1"), filters.orderBy())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, scope.UserID, filters.limit(), filters.offSet(), scope.WorkspaceID)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	totalRecords := 0
+
+	lists := []*List{}
+	for rows.Next() {
+		var list List
+		err := rows.Scan(
+			&totalRecords,
+			&list.ID,
+			&list.CreatedAt,
+			&list.Name,
+			&list.Description,
+			&list.Todos,
+			&list.OwnerID,
+			&list.WorkspaceID,
+			&list.Access,
+			&list.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		lists = append(lists, &list)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetaData(totalRecords, filters.Page, filters.PageSize)
+	return lists, metadata, nil
+}
+
+// Update() renames or re-describes a list the user is allowed to change
+func (m ListModel) Update(list *List, scope Scope) error {
+	query := `
+		UPDATE lists
+		SET name = $1, description = $2, version = version + 1
+		WHERE id = $3
+		AND workspace_id = $5
+		AND ` + fmt.Sprintf(listEditAccess, "$4") + `
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, list.Name, list.Description, list.ID, scope.UserID, scope.WorkspaceID).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete() removes a list. Its todos are kept and simply no longer belong to a list.
+// Only the owner of a list may delete it
+func (m ListModel) Delete(id int64, scope Scope) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM lists
+		WHERE id = $1 AND owner_id = $2 AND workspace_id = $3
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, scope.UserID, scope.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
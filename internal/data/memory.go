@@ -0,0 +1,535 @@
+// File: todo/internal/data/memory.go
+package data
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MemoryTodoStore keeps todos in memory. It behaves like TodoModel with two
+// exceptions: lists and shares live in the database, so a todo is only visible to
+// its owner and cannot be put on a list, and no history is recorded
+type MemoryTodoStore struct {
+	mu     sync.Mutex
+	nextID int64
+	todos  map[int64]Todo
+}
+
+// NewMemoryTodoStore() creates an empty in-memory store
+func NewMemoryTodoStore() *MemoryTodoStore {
+	return &MemoryTodoStore{todos: make(map[int64]Todo)}
+}
+
+// Insert() allows us to create a new todo
+func (s *MemoryTodoStore) Insert(todo *Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insert(todo)
+}
+
+func (s *MemoryTodoStore) insert(todo *Todo) error {
+	if todo.ListID != nil {
+		return ErrListNotFound
+	}
+	s.nextID++
+	todo.ID = s.nextID
+	todo.CreatedAt = time.Now().Truncate(time.Second)
+	todo.CompletedAt = nil
+	if todo.Status == StatusDone {
+		completedAt := todo.CreatedAt
+		todo.CompletedAt = &completedAt
+	}
+	todo.Tags = sortedTags(todo.Tags)
+	todo.Checklist = Checklist{}
+	todo.Version = 1
+	todo.Access = AccessOwner
+	s.todos[todo.ID] = *todo
+	return nil
+}
+
+// Get() allows us to retrieve a specific task the user is allowed to see in their workspace
+func (s *MemoryTodoStore) Get(id int64, scope Scope) (*Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id, scope)
+}
+
+func (s *MemoryTodoStore) get(id int64, scope Scope) (*Todo, error) {
+	todo, ok := s.todos[id]
+	if !ok || !todo.ownedBy(scope) || todo.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	todo.Access = AccessOwner
+	return &todo, nil
+}
+
+// Update() changes a todo as long as it is still at the version that was read
+func (s *MemoryTodoStore) Update(todo *Todo, scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(todo, scope)
+}
+
+func (s *MemoryTodoStore) update(todo *Todo, scope Scope) error {
+	stored, ok := s.todos[todo.ID]
+	if !ok || !stored.ownedBy(scope) || stored.DeletedAt != nil {
+		return ErrRecordNotFound
+	}
+	if stored.Version != todo.Version {
+		return ErrEditConflict
+	}
+	if todo.ListID != nil {
+		return ErrListNotFound
+	}
+
+	//completion time is kept for as long as the todo stays done
+	switch {
+	case todo.Status != StatusDone:
+		todo.CompletedAt = nil
+	case stored.CompletedAt != nil:
+		todo.CompletedAt = stored.CompletedAt
+	default:
+		now := time.Now().Truncate(time.Second)
+		todo.CompletedAt = &now
+	}
+
+	stored.Title = todo.Title
+	stored.Description = todo.Description
+	stored.Status = todo.Status
+	stored.Priority = todo.Priority
+	stored.CompletedAt = todo.CompletedAt
+	stored.StartsAt = todo.StartsAt
+	stored.DueAt = todo.DueAt
+	stored.Tags = sortedTags(todo.Tags)
+	stored.Recurrence = todo.Recurrence
+	stored.NextID = todo.NextID
+	stored.Version++
+	s.todos[todo.ID] = stored
+
+	todo.Version = stored.Version
+	todo.Tags = sortedTags(todo.Tags)
+	return nil
+}
+
+// Delete() moves a todo to the trash. Only the owner of a todo may delete it, and
+// only while it is still at the given version
+func (s *MemoryTodoStore) Delete(id int64, version int32, scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(id, version, scope)
+}
+
+func (s *MemoryTodoStore) delete(id int64, version int32, scope Scope) error {
+	todo, ok := s.todos[id]
+	if !ok || !todo.ownedBy(scope) || todo.DeletedAt != nil {
+		return ErrRecordNotFound
+	}
+	if todo.Version != version {
+		return ErrEditConflict
+	}
+	now := time.Now().Truncate(time.Second)
+	todo.DeletedAt = &now
+	s.todos[id] = todo
+	return nil
+}
+
+// GetAll() lists the todos the user is allowed to see in their workspace
+func (s *MemoryTodoStore) GetAll(scope Scope, search TodoSearch, filters Filters) ([]*Todo, Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := []*Todo{}
+	for _, todo := range s.todos {
+		if todo.ownedBy(scope) && todo.DeletedAt == nil && search.matches(&todo) {
+			todo := todo
+			todo.Access = AccessOwner
+			tasks = append(tasks, &todo)
+		}
+	}
+
+	keys := filters.sortKeys()
+	if !filters.UseCursor {
+		sortTodos(tasks, keys, false)
+		return pageTodos(tasks, filters)
+	}
+
+	//In cursor mode only the todos after (or before) the cursor are kept
+	var position cursor
+	if filters.Cursor != "" {
+		var err error
+		position, err = decodeCursor(filters.Cursor)
+		if err != nil || len(position.Values) != len(keys) {
+			return nil, Metadata{}, ErrInvalidCursor
+		}
+		at, err := cursorTodo(keys, position.Values)
+		if err != nil {
+			return nil, Metadata{}, ErrInvalidCursor
+		}
+		after := tasks[:0]
+		for _, todo := range tasks {
+			if compareTodos(todo, at, keys, position.Before) > 0 {
+				after = append(after, todo)
+			}
+		}
+		tasks = after
+	}
+	sortTodos(tasks, keys, position.Before)
+	if len(tasks) > filters.PageSize+1 {
+		tasks = tasks[:filters.PageSize+1]
+	}
+	tasks, metadata := cursorPage(tasks, filters, keys, position)
+	return tasks, metadata, nil
+}
+
+// Begin() starts a batch. The store stays locked until the batch is committed or
+// rolled back, so a batch never sees a half written state from another request
+func (s *MemoryTodoStore) Begin() (TodoBatch, error) {
+	s.mu.Lock()
+	b := &memoryTodoBatch{store: s}
+	b.begin = s.save()
+	return b, nil
+}
+
+// GetTrash() lists the todos the user has deleted from their workspace
+func (s *MemoryTodoStore) GetTrash(scope Scope, filters Filters) ([]*Todo, Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := []*Todo{}
+	for _, todo := range s.todos {
+		if todo.ownedBy(scope) && todo.DeletedAt != nil {
+			todo := todo
+			todo.Access = AccessOwner
+			tasks = append(tasks, &todo)
+		}
+	}
+	sortTodos(tasks, filters.sortKeys(), false)
+	return pageTodos(tasks, filters)
+}
+
+// Restore() takes a todo back out of the trash
+func (s *MemoryTodoStore) Restore(id int64, scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[id]
+	if !ok || !todo.ownedBy(scope) || todo.DeletedAt == nil {
+		return ErrRecordNotFound
+	}
+	todo.DeletedAt = nil
+	todo.Version++
+	s.todos[id] = todo
+	return nil
+}
+
+// Purge() permanently removes a todo in the trash
+func (s *MemoryTodoStore) Purge(id int64, scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[id]
+	if !ok || !todo.ownedBy(scope) || todo.DeletedAt == nil {
+		return ErrRecordNotFound
+	}
+	delete(s.todos, id)
+	return nil
+}
+
+// PurgeExpired() permanently removes every todo that has been in the trash for
+// longer than the retention period and reports how many were removed
+func (s *MemoryTodoStore) PurgeExpired(retention time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	var purged int64
+	for id, todo := range s.todos {
+		if todo.DeletedAt != nil && todo.DeletedAt.Before(cutoff) {
+			delete(s.todos, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// memoryState is a copy of the store used to undo a batch
+type memoryState struct {
+	nextID int64
+	todos  map[int64]Todo
+}
+
+func (s *MemoryTodoStore) save() memoryState {
+	todos := make(map[int64]Todo, len(s.todos))
+	for id, todo := range s.todos {
+		todos[id] = todo
+	}
+	return memoryState{nextID: s.nextID, todos: todos}
+}
+
+func (s *MemoryTodoStore) load(state memoryState) {
+	s.nextID = state.nextID
+	s.todos = state.todos
+}
+
+// memoryTodoBatch is the TodoBatch of MemoryTodoStore. It works on the store
+// directly while holding its lock and keeps copies to roll back to
+type memoryTodoBatch struct {
+	store     *MemoryTodoStore
+	begin     memoryState
+	savepoint *memoryState
+	done      bool
+}
+
+func (b *memoryTodoBatch) Insert(todo *Todo) error {
+	return b.store.insert(todo)
+}
+
+func (b *memoryTodoBatch) Get(id int64, scope Scope) (*Todo, error) {
+	return b.store.get(id, scope)
+}
+
+func (b *memoryTodoBatch) Update(todo *Todo, scope Scope) error {
+	return b.store.update(todo, scope)
+}
+
+func (b *memoryTodoBatch) Delete(id int64, version int32, scope Scope) error {
+	return b.store.delete(id, version, scope)
+}
+
+func (b *memoryTodoBatch) Savepoint() error {
+	state := b.store.save()
+	b.savepoint = &state
+	return nil
+}
+
+func (b *memoryTodoBatch) ReleaseSavepoint() error {
+	b.savepoint = nil
+	return nil
+}
+
+func (b *memoryTodoBatch) RollbackToSavepoint() error {
+	if b.savepoint != nil {
+		b.store.load(*b.savepoint)
+		b.savepoint = nil
+	}
+	return nil
+}
+
+func (b *memoryTodoBatch) Commit() error {
+	if b.done {
+		return nil
+	}
+	b.done = true
+	b.store.mu.Unlock()
+	return nil
+}
+
+// Rollback() abandons the batch. It is safe to call after Commit()
+func (b *memoryTodoBatch) Rollback() error {
+	if b.done {
+		return nil
+	}
+	b.done = true
+	b.store.load(b.begin)
+	b.store.mu.Unlock()
+	return nil
+}
+
+// ownedBy() reports whether the todo belongs to the user in the workspace of the scope
+func (todo *Todo) ownedBy(scope Scope) bool {
+	return todo.OwnerID == scope.UserID && todo.WorkspaceID == scope.WorkspaceID
+}
+
+// matches() applies the search criteria the way the GetAll() query does
+func (search TodoSearch) matches(todo *Todo) bool {
+	switch {
+	case search.Title != "" && !containsWords(todo.Title, search.Title):
+		return false
+	case search.Description != "" && !containsWords(todo.Description, search.Description):
+		return false
+	case search.Status != "" && string(todo.Status) != search.Status:
+		return false
+	case search.Priority != 0 && todo.Priority != search.Priority:
+		return false
+	case search.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*search.DueBefore)):
+		return false
+	case search.DueAfter != nil && (todo.DueAt == nil || !todo.DueAt.After(*search.DueAfter)):
+		return false
+	case search.ListID != 0 && (todo.ListID == nil || *todo.ListID != search.ListID):
+		return false
+	}
+	if search.Overdue {
+		open := todo.Status != StatusDone && todo.Status != StatusCancelled
+		if todo.DueAt == nil || !todo.DueAt.Before(time.Now()) || !open {
+			return false
+		}
+	}
+	if len(search.Tags) > 0 {
+		found := 0
+		for _, tag := range todo.Tags {
+			for _, wanted := range search.Tags {
+				if tag == wanted {
+					found++
+				}
+			}
+		}
+		needed := 1
+		if search.AllTags {
+			needed = len(search.Tags)
+		}
+		if found < needed {
+			return false
+		}
+	}
+	return true
+}
+
+// searchWords() splits text into lower case words the way the 'simple' text search configuration does
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsWords() is the in-memory stand-in for plainto_tsquery: every word of
+// the query has to appear as a word of the text
+func containsWords(text, query string) bool {
+	words := map[string]bool{}
+	for _, word := range searchWords(text) {
+		words[word] = true
+	}
+	for _, word := range searchWords(query) {
+		if !words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTodos() orders todos by the sort keys the way the ORDER BY built by orderBy() does
+func sortTodos(tasks []*Todo, keys []sortKey, reverse bool) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return compareTodos(tasks[i], tasks[j], keys, reverse) < 0
+	})
+}
+
+// compareTodos() compares two todos key by key, with NULLs last going forwards
+// and first going backwards
+func compareTodos(a, b *Todo, keys []sortKey, reverse bool) int {
+	for _, key := range keys {
+		var c int
+		switch key.column {
+		case "id":
+			c = compareInts(a.ID, b.ID)
+		case "title":
+			c = strings.Compare(a.Title, b.Title)
+		case "description":
+			c = strings.Compare(a.Description, b.Description)
+		case "status":
+			c = strings.Compare(string(a.Status), string(b.Status))
+		case "priority":
+			c = compareInts(int64(a.Priority), int64(b.Priority))
+		default:
+			at, bt := a.sortTime(key.column), b.sortTime(key.column)
+			switch {
+			case at == nil && bt == nil:
+				continue
+			case at == nil || bt == nil:
+				if (at == nil) != reverse {
+					return 1
+				}
+				return -1
+			case at.Before(*bt):
+				c = -1
+			case at.After(*bt):
+				c = 1
+			}
+		}
+		if key.desc != reverse {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// sortTime() returns the todo's value in one of the time sort columns
+func (todo *Todo) sortTime(column string) *time.Time {
+	switch column {
+	case "dueat":
+		return todo.DueAt
+	case "startsat":
+		return todo.StartsAt
+	case "deletedat":
+		return todo.DeletedAt
+	}
+	return nil
+}
+
+// cursorTodo() turns the values stored in a cursor back into a todo that can be compared against
+func cursorTodo(keys []sortKey, values []*string) (*Todo, error) {
+	var todo Todo
+	for i, key := range keys {
+		value := values[i]
+		if value == nil {
+			continue
+		}
+		var err error
+		switch key.column {
+		case "id":
+			todo.ID, err = strconv.ParseInt(*value, 10, 64)
+		case "title":
+			todo.Title = *value
+		case "description":
+			todo.Description = *value
+		case "status":
+			todo.Status = Status(*value)
+		case "priority":
+			var p int
+			p, err = strconv.Atoi(*value)
+			todo.Priority = Priority(p)
+		case "dueat", "startsat":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339Nano, *value)
+			if key.column == "dueat" {
+				todo.DueAt = &t
+			} else {
+				todo.StartsAt = &t
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &todo, nil
+}
+
+// pageTodos() cuts a page out of sorted todos using page and page_size
+func pageTodos(tasks []*Todo, filters Filters) ([]*Todo, Metadata, error) {
+	metadata := calculateMetaData(len(tasks), filters.Page, filters.PageSize)
+	start := filters.offSet()
+	if start > len(tasks) {
+		start = len(tasks)
+	}
+	end := start + filters.limit()
+	if end > len(tasks) {
+		end = len(tasks)
+	}
+	return tasks[start:end], metadata, nil
+}
@@ -13,12 +13,32 @@ var (
 
 // A wrapper for out data models
 type Models struct {
-	Todos TodoModel
+	Todos       TodoStore
+	Tags        TagModel
+	Items       ItemModel
+	Lists       ListModel
+	Users       UserModel
+	Tokens      TokenModel
+	Permissions PermissionModel
+	Shares      ShareModel
+	Workspaces  WorkspaceModel
+	APIKeys     APIKeyModel
+	Revisions   RevisionModel
 }
 
 // NewModels() allows us to create a new model
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Todos: TodoModel{DB: db},
+		Todos:       TodoModel{DB: db},
+		Tags:        TagModel{DB: db},
+		Items:       ItemModel{DB: db},
+		Lists:       ListModel{DB: db},
+		Users:       UserModel{DB: db},
+		Tokens:      TokenModel{DB: db},
+		Permissions: PermissionModel{DB: db},
+		Shares:      ShareModel{DB: db},
+		Workspaces:  WorkspaceModel{DB: db},
+		APIKeys:     APIKeyModel{DB: db},
+		Revisions:   RevisionModel{DB: db},
 	}
 }
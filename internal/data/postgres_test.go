@@ -0,0 +1,57 @@
+// File: todo/internal/data/postgres_test.go
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"todo.kegodo.net/internal/migrate"
+	"todo.kegodo.net/migrations"
+)
+
+// testPostgres() opens the database named by TODOS_TEST_DSN and brings its schema
+// up to date, skipping the test when the variable is not set. Tests add rows with
+// unique names and never remove them, so the database should be a throwaway one
+func testPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TODOS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("TODOS_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	files, err := migrations.Files("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.New(db, "postgres", files)
+	if err == nil {
+		err = m.Up()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testRegister() creates a user with a personal workspace and returns its scope
+func testRegister(t *testing.T, models Models, name string) Scope {
+	t.Helper()
+	user := &User{Name: name, Email: fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	workspace := &Workspace{Name: "Personal"}
+	if err := models.Users.Register(user, workspace, PermissionTodosRead, PermissionTodosWrite); err != nil {
+		t.Fatal(err)
+	}
+	return Scope{UserID: user.ID, WorkspaceID: workspace.ID}
+}
@@ -0,0 +1,67 @@
+// File: todo/internal/data/priority.go
+package data
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidPriority is returned when a priority cannot be decoded from JSON
+var ErrInvalidPriority = errors.New("invalid priority, must be one of low, normal, high or urgent")
+
+// Priority is stored as a small integer so that it sorts naturally in the database
+type Priority int16
+
+const (
+	PriorityLow Priority = iota + 1
+	PriorityNormal
+	PriorityHigh
+	PriorityUrgent
+)
+
+// The names used for each priority in the JSON representation
+var priorityNames = map[Priority]string{
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// ParsePriority() converts a priority name into a Priority
+func ParsePriority(name string) (Priority, error) {
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, ErrInvalidPriority
+}
+
+// Valid() reports whether p is one of the known priorities
+func (p Priority) Valid() bool {
+	_, ok := priorityNames[p]
+	return ok
+}
+
+func (p Priority) String() string {
+	return priorityNames[p]
+}
+
+// MarshalJSON() writes the priority out as its name
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(p.String())), nil
+}
+
+// UnmarshalJSON() reads the priority in from its name
+func (p *Priority) UnmarshalJSON(jsonValue []byte) error {
+	name, err := strconv.Unquote(string(jsonValue))
+	if err != nil {
+		return ErrInvalidPriority
+	}
+	parsed, err := ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
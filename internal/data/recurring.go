@@ -0,0 +1,57 @@
+// File: todo/internal/data/recurring.go
+package data
+
+import (
+	"todo.kegodo.net/internal/recurrence"
+	"todo.kegodo.net/internal/validator"
+)
+
+func ValidateRecurrence(v *validator.Validator, todo *Todo) {
+	if todo.Recurrence == "" {
+		return
+	}
+	if _, err := recurrence.Parse(todo.Recurrence); err != nil {
+		v.AddError("recurrence", err.Error())
+		return
+	}
+	v.Check(todo.DueAt != nil, "recurrence", "requires due_at to be provided")
+}
+
+// NextOccurrence() builds the todo that follows a recurring todo once it is done.
+// The copy is due on the next date of the rule, keeps the same gap between its start
+// and due dates, and carries the rule forward with any COUNT reduced by one.
+// Due dates are stored without a time zone, so the series keeps the same UTC time
+// of day rather than the same local time across daylight saving changes.
+// It returns false when the todo does not recur or the series has ended
+func (todo *Todo) NextOccurrence() (*Todo, bool) {
+	if todo.Recurrence == "" || todo.DueAt == nil {
+		return nil, false
+	}
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, false
+	}
+
+	dueAt, rest, ok := rule.Advance(*todo.DueAt)
+	if !ok {
+		return nil, false
+	}
+
+	next := &Todo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Status:      StatusTodo,
+		Priority:    todo.Priority,
+		DueAt:       &dueAt,
+		Tags:        append([]string{}, todo.Tags...),
+		ListID:      todo.ListID,
+		Recurrence:  rest.String(),
+		OwnerID:     todo.OwnerID,
+		WorkspaceID: todo.WorkspaceID,
+	}
+	if todo.StartsAt != nil {
+		startsAt := dueAt.Add(todo.StartsAt.Sub(*todo.DueAt))
+		next.StartsAt = &startsAt
+	}
+	return next, true
+}
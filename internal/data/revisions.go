@@ -0,0 +1,264 @@
+// File: todo/internal/data/revisions.go
+package data
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The actions recorded in the history of a todo
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// TodoSnapshot holds the fields of a todo that are tracked in its history
+type TodoSnapshot struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      Status     `json:"status"`
+	Priority    Priority   `json:"priority"`
+	StartsAt    *time.Time `json:"starts_at"`
+	DueAt       *time.Time `json:"due_at"`
+	Tags        []string   `json:"tags"`
+	ListID      *int64     `json:"list_id"`
+	Recurrence  string     `json:"recurrence"`
+}
+
+// Change holds the value of a field before and after a revision
+type Change struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// Revision is one entry in the history of a todo
+type Revision struct {
+	ID        int64             `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	TodoID    int64             `json:"todo_id"`
+	Version   int32             `json:"version"`
+	Action    string            `json:"action"`
+	ActorID   int64             `json:"actor_id,omitempty"`
+	ActorName string            `json:"actor_name,omitempty"`
+	Changes   map[string]Change `json:"changes"`
+}
+
+// snapshot() captures the tracked fields of a todo. Times are stored the way the
+// database keeps them and tags in order so that equal values compare equal
+func (todo *Todo) snapshot() TodoSnapshot {
+	tags := append([]string{}, todo.Tags...)
+	sort.Strings(tags)
+	return TodoSnapshot{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Status:      todo.Status,
+		Priority:    todo.Priority,
+		StartsAt:    snapshotTime(todo.StartsAt),
+		DueAt:       snapshotTime(todo.DueAt),
+		Tags:        tags,
+		ListID:      todo.ListID,
+		Recurrence:  todo.Recurrence,
+	}
+}
+
+// ApplyTo() copies the tracked fields of the snapshot onto a todo
+func (s *TodoSnapshot) ApplyTo(todo *Todo) {
+	todo.Title = s.Title
+	todo.Description = s.Description
+	todo.Status = s.Status
+	todo.Priority = s.Priority
+	todo.StartsAt = s.StartsAt
+	todo.DueAt = s.DueAt
+	todo.Tags = s.Tags
+	todo.ListID = s.ListID
+	todo.Recurrence = s.Recurrence
+}
+
+func snapshotTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Truncate(time.Second)
+	return &s
+}
+
+// The diffSnapshots() function lists the fields that differ between two snapshots.
+// A nil before (a newly created todo) reports every field as changed
+func diffSnapshots(before *TodoSnapshot, after TodoSnapshot) (map[string]Change, error) {
+	oldFields := map[string]json.RawMessage{}
+	if before != nil {
+		js, err := json.Marshal(before)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(js, &oldFields); err != nil {
+			return nil, err
+		}
+	}
+	js, err := json.Marshal(after)
+	if err != nil {
+		return nil, err
+	}
+	newFields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(js, &newFields); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for field, value := range newFields {
+		if before == nil || !bytes.Equal(oldFields[field], value) {
+			changes[field] = Change{Old: oldFields[field], New: value}
+		}
+	}
+	return changes, nil
+}
+
+// loadSnapshot() reads the tracked fields of a todo as they are before a change,
+// locking the row until the transaction ends
+func loadSnapshot(ctx context.Context, tx *sql.Tx, id int64) (*TodoSnapshot, error) {
+	query := `
+		SELECT title, description, status, priority, startsat, dueat, list_id, recurrence,
+		       ARRAY(SELECT tags.name FROM tags JOIN todo_tags ON todo_tags.tag_id = tags.id
+		             WHERE todo_tags.todo_id = todos.id ORDER BY tags.name)
+		FROM todos
+		WHERE id = $1
+		FOR UPDATE`
+
+	var todo Todo
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&todo.Title,
+		&todo.Description,
+		&todo.Status,
+		&todo.Priority,
+		&todo.StartsAt,
+		&todo.DueAt,
+		&todo.ListID,
+		&todo.Recurrence,
+		pq.Array(&todo.Tags),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	snapshot := todo.snapshot()
+	return &snapshot, nil
+}
+
+// recordRevision() adds an entry to the history of a todo as part of the
+// transaction that made the change
+func recordRevision(ctx context.Context, tx *sql.Tx, todoID int64, version int32, action string, actorID int64, before *TodoSnapshot, after TodoSnapshot) error {
+	changes, err := diffSnapshots(before, after)
+	if err != nil {
+		return err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	snapshotJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO todo_revisions (todo_id, version, action, actor_id, changes, snapshot)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)`
+
+	_, err = tx.ExecContext(ctx, query, todoID, version, action, actorID, changesJSON, snapshotJSON)
+	return err
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForTodo() returns the history of a todo, oldest first
+func (m RevisionModel) GetAllForTodo(todoID int64) ([]*Revision, error) {
+	query := `
+		SELECT todo_revisions.id, todo_revisions.createdat, todo_revisions.todo_id, todo_revisions.version,
+		       todo_revisions.action, COALESCE(todo_revisions.actor_id, 0), COALESCE(users.name, ''),
+		       todo_revisions.changes
+		FROM todo_revisions
+		LEFT JOIN users ON users.id = todo_revisions.actor_id
+		WHERE todo_revisions.todo_id = $1
+		ORDER BY todo_revisions.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+	for rows.Next() {
+		var revision Revision
+		var changes []byte
+		err := rows.Scan(
+			&revision.ID,
+			&revision.CreatedAt,
+			&revision.TodoID,
+			&revision.Version,
+			&revision.Action,
+			&revision.ActorID,
+			&revision.ActorName,
+			&changes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(changes, &revision.Changes); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetAtVersion() returns the tracked fields of a todo as they were at the given version
+func (m RevisionModel) GetAtVersion(todoID int64, version int32) (*TodoSnapshot, error) {
+	query := `
+		SELECT snapshot
+		FROM todo_revisions
+		WHERE todo_id = $1 AND version = $2
+		ORDER BY id DESC
+		LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var js []byte
+	err := m.DB.QueryRowContext(ctx, query, todoID, version).Scan(&js)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	var snapshot TodoSnapshot
+	if err = json.Unmarshal(js, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
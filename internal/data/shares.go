@@ -0,0 +1,155 @@
+// File: todo/internal/data/shares.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo.kegodo.net/internal/validator"
+)
+
+// Access describes what a user may do with a todo or list
+type Access string
+
+const (
+	AccessView  Access = "view"
+	AccessEdit  Access = "edit"
+	AccessOwner Access = "owner"
+)
+
+// CanEdit() reports whether the access level allows changes
+func (a Access) CanEdit() bool {
+	return a == AccessEdit || a == AccessOwner
+}
+
+// ShareKind names what is being shared, and so which table holds the grants
+type ShareKind string
+
+const (
+	ShareTodo ShareKind = "todo"
+	ShareList ShareKind = "list"
+)
+
+// The grants tables and the column naming the shared resource in each
+var shareTables = map[ShareKind]struct{ table, column string }{
+	ShareTodo: {"todo_shares", "todo_id"},
+	ShareList: {"list_shares", "list_id"},
+}
+
+// The SQL predicates deciding who can see or change a todo. %[1]s is the
+// placeholder of the user id. A todo is visible to its owner, to anyone it has
+// been shared with, and to the owner of, or anyone sharing, the list it is on
+const todoViewAccess = `(todos.owner_id = %[1]s
+		OR EXISTS (SELECT 1 FROM todo_shares WHERE todo_shares.todo_id = todos.id AND todo_shares.user_id = %[1]s)
+		OR EXISTS (SELECT 1 FROM lists WHERE lists.id = todos.list_id AND lists.owner_id = %[1]s)
+		OR EXISTS (SELECT 1 FROM list_shares WHERE list_shares.list_id = todos.list_id AND list_shares.user_id = %[1]s))`
+
+const todoEditAccess = `(todos.owner_id = %[1]s
+		OR EXISTS (SELECT 1 FROM todo_shares WHERE todo_shares.todo_id = todos.id AND todo_shares.user_id = %[1]s AND todo_shares.access = 'edit')
+		OR EXISTS (SELECT 1 FROM lists WHERE lists.id = todos.list_id AND lists.owner_id = %[1]s)
+		OR EXISTS (SELECT 1 FROM list_shares WHERE list_shares.list_id = todos.list_id AND list_shares.user_id = %[1]s AND list_shares.access = 'edit'))`
+
+const listViewAccess = `(lists.owner_id = %[1]s
+		OR EXISTS (SELECT 1 FROM list_shares WHERE list_shares.list_id = lists.id AND list_shares.user_id = %[1]s))`
+
+const listEditAccess = `(lists.owner_id = %[1]s
+		OR EXISTS (SELECT 1 FROM list_shares WHERE list_shares.list_id = lists.id AND list_shares.user_id = %[1]s AND list_shares.access = 'edit'))`
+
+// accessColumn() builds the expression reporting the access level of a user
+func accessColumn(table, placeholder, editAccess string) string {
+	return fmt.Sprintf(`CASE WHEN %s.owner_id = %s THEN 'owner' WHEN %s THEN 'edit' ELSE 'view' END`,
+		table, placeholder, fmt.Sprintf(editAccess, placeholder))
+}
+
+// Share struct supports the information for a grant given to another user
+type Share struct {
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Access    Access    `json:"access"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ValidateShareAccess(v *validator.Validator, access Access) {
+	v.Check(access == AccessView || access == AccessEdit, "access", "must be either view or edit")
+}
+
+type ShareModel struct {
+	DB *sql.DB
+}
+
+// GetAll() lists the users a todo or list has been shared with
+func (m ShareModel) GetAll(kind ShareKind, resourceID int64) ([]*Share, error) {
+	t := shareTables[kind]
+	query := fmt.Sprintf(`
+		SELECT users.id, users.name, users.email, %[1]s.access, %[1]s.createdat
+		FROM %[1]s
+		INNER JOIN users ON users.id = %[1]s.user_id
+		WHERE %[1]s.%[2]s = $1
+		ORDER BY users.name ASC, users.id ASC`, t.table, t.column)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*Share{}
+	for rows.Next() {
+		var share Share
+		err := rows.Scan(&share.UserID, &share.Name, &share.Email, &share.Access, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, &share)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// Grant() shares a todo or list with a user, changing the access level of an existing grant
+func (m ShareModel) Grant(kind ShareKind, resourceID int64, share *Share) error {
+	t := shareTables[kind]
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, user_id, access)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (%[2]s, user_id) DO UPDATE SET access = EXCLUDED.access
+		RETURNING createdat`, t.table, t.column)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, resourceID, share.UserID, share.Access).Scan(&share.CreatedAt)
+}
+
+// Revoke() removes the grant a user holds on a todo or list
+func (m ShareModel) Revoke(kind ShareKind, resourceID, userID int64) error {
+	t := shareTables[kind]
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE %[2]s = $1 AND user_id = $2`, t.table, t.column)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, resourceID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
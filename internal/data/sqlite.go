@@ -0,0 +1,523 @@
+// File: todo/internal/data/sqlite.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLiteTodoStore keeps todos in a SQLite database for single-user and offline
+// deployments. Like MemoryTodoStore it has no lists, shares or history, so a todo
+// is only visible to its owner
+type SQLiteTodoStore struct {
+	DB *sql.DB
+}
+
+// sqliteTime is how timestamps are stored in SQLite. The fixed width UTC text
+// sorts in the same order as the times themselves
+const sqliteTime = "2006-01-02T15:04:05Z"
+
+func sqliteTimeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Truncate(time.Second).Format(sqliteTime)
+}
+
+// sqliteTimeScanner scans a timestamp stored as text into a *time.Time
+type sqliteTimeScanner struct {
+	dst **time.Time
+}
+
+func (s sqliteTimeScanner) Scan(src interface{}) error {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		*s.dst = nil
+		return nil
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	case time.Time:
+		*s.dst = &value
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	t, err := time.Parse(sqliteTime, text)
+	if err != nil {
+		return err
+	}
+	*s.dst = &t
+	return nil
+}
+
+// sqliteArgs collects query arguments and hands out numbered ?NNN placeholders,
+// which unlike plain ? can be referred to more than once
+type sqliteArgs []interface{}
+
+func (a *sqliteArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("?%d", len(*a))
+}
+
+// The columns of a todo as read by scanSQLiteTodo()
+const sqliteTodoColumns = `id, createdat, title, description, status, priority, completedat, startsat, dueat, deletedat,
+		recurrence, next_id, owner_id, workspace_id,
+		(SELECT json_group_array(name) FROM (SELECT name FROM todo_tags WHERE todo_tags.todo_id = todos.id ORDER BY name)),
+		version`
+
+// scanSQLiteTodo() reads a row selected with sqliteTodoColumns, after any leading columns
+func scanSQLiteTodo(scan func(dest ...interface{}) error, leading ...interface{}) (*Todo, error) {
+	todo := Todo{Access: AccessOwner}
+	var createdAt *time.Time
+	var tags string
+	dest := append(leading,
+		&todo.ID,
+		sqliteTimeScanner{&createdAt},
+		&todo.Title,
+		&todo.Description,
+		&todo.Status,
+		&todo.Priority,
+		sqliteTimeScanner{&todo.CompletedAt},
+		sqliteTimeScanner{&todo.StartsAt},
+		sqliteTimeScanner{&todo.DueAt},
+		sqliteTimeScanner{&todo.DeletedAt},
+		&todo.Recurrence,
+		&todo.NextID,
+		&todo.OwnerID,
+		&todo.WorkspaceID,
+		&tags,
+		&todo.Version,
+	)
+	if err := scan(dest...); err != nil {
+		return nil, err
+	}
+	if createdAt != nil {
+		todo.CreatedAt = *createdAt
+	}
+	if err := json.Unmarshal([]byte(tags), &todo.Tags); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// ftsQuery() builds an FTS5 query that, like plainto_tsquery, needs every word of
+// the search to appear in the column. It is empty when the search has no words
+func ftsQuery(column, search string) string {
+	words := searchWords(search)
+	for i, word := range words {
+		words[i] = fmt.Sprintf(`%s : "%s"`, column, strings.ReplaceAll(word, `"`, `""`))
+	}
+	return strings.Join(words, " AND ")
+}
+
+// inTx() runs fn in a transaction that is committed when fn succeeds
+func (m SQLiteTodoStore) inTx(fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Insert() allows us to create a new todo
+func (m SQLiteTodoStore) Insert(todo *Todo) error {
+	return m.inTx(func(ctx context.Context, tx *sql.Tx) error {
+		return sqliteInsertTodo(ctx, tx, todo)
+	})
+}
+
+func sqliteInsertTodo(ctx context.Context, q queryer, todo *Todo) error {
+	//there are no lists to put the todo on
+	if todo.ListID != nil {
+		return ErrListNotFound
+	}
+
+	query := `
+		INSERT INTO todos (createdat, title, description, status, completedat, startsat, dueat, priority, recurrence, owner_id, workspace_id)
+		VALUES (?1, ?2, ?3, ?4, CASE WHEN ?4 = 'done' THEN ?1 END, ?5, ?6, ?7, ?8, ?9, ?10)
+		RETURNING id, version`
+
+	now := time.Now().UTC().Truncate(time.Second)
+	args := []interface{}{now.Format(sqliteTime), todo.Title, todo.Description, todo.Status, sqliteTimeValue(todo.StartsAt), sqliteTimeValue(todo.DueAt), todo.Priority, todo.Recurrence, todo.OwnerID, todo.WorkspaceID}
+	err := q.QueryRowContext(ctx, query, args...).Scan(&todo.ID, &todo.Version)
+	if err != nil {
+		return err
+	}
+
+	todo.CreatedAt = now
+	todo.CompletedAt = nil
+	if todo.Status == StatusDone {
+		todo.CompletedAt = &now
+	}
+	todo.Access = AccessOwner
+	todo.Tags = sortedTags(todo.Tags)
+	return setSQLiteTags(ctx, q, todo.ID, todo.Tags)
+}
+
+// setSQLiteTags() replaces the tags of a todo
+func setSQLiteTags(ctx context.Context, q queryer, todoID int64, names []string) error {
+	_, err := q.ExecContext(ctx, `DELETE FROM todo_tags WHERE todo_id = ?1`, todoID)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		_, err = q.ExecContext(ctx, `INSERT INTO todo_tags (todo_id, name) VALUES (?1, ?2)`, todoID, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get() allows us to retrieve a specific task the user owns in their workspace
+func (m SQLiteTodoStore) Get(id int64, scope Scope) (*Todo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sqliteGetTodo(ctx, m.DB, id, scope)
+}
+
+func sqliteGetTodo(ctx context.Context, q queryer, id int64, scope Scope) (*Todo, error) {
+	query := `
+		SELECT ` + sqliteTodoColumns + `
+		FROM todos
+		WHERE id = ?1 AND owner_id = ?2 AND workspace_id = ?3
+		AND deletedat IS NULL`
+
+	todo, err := scanSQLiteTodo(q.QueryRowContext(ctx, query, id, scope.UserID, scope.WorkspaceID).Scan)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return todo, nil
+}
+
+// Update() changes a todo as long as it is still at the version that was read
+func (m SQLiteTodoStore) Update(todo *Todo, scope Scope) error {
+	return m.inTx(func(ctx context.Context, tx *sql.Tx) error {
+		return sqliteUpdateTodo(ctx, tx, todo, scope)
+	})
+}
+
+func sqliteUpdateTodo(ctx context.Context, q queryer, todo *Todo, scope Scope) error {
+	if todo.ListID != nil {
+		return ErrListNotFound
+	}
+
+	query := `
+		UPDATE todos
+		SET title = ?1, description = ?2, status = ?3,
+		    completedat = CASE WHEN ?3 = 'done' THEN COALESCE(completedat, ?4) END,
+		    startsat = ?5, dueat = ?6, priority = ?7, recurrence = ?8,
+		    next_id = ?13, version = version + 1
+		WHERE id = ?9 AND version = ?10 AND owner_id = ?11 AND workspace_id = ?12
+		AND deletedat IS NULL
+		RETURNING completedat, version`
+
+	now := time.Now().UTC().Truncate(time.Second)
+	args := []interface{}{todo.Title, todo.Description, todo.Status, now.Format(sqliteTime), sqliteTimeValue(todo.StartsAt), sqliteTimeValue(todo.DueAt), todo.Priority, todo.Recurrence, todo.ID, todo.Version, scope.UserID, scope.WorkspaceID, todo.NextID}
+	err := q.QueryRowContext(ctx, query, args...).Scan(sqliteTimeScanner{&todo.CompletedAt}, &todo.Version)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		//a todo the user can still reach has been changed by someone else
+		var exists bool
+		err = q.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM todos
+				WHERE id = ?1 AND owner_id = ?2 AND workspace_id = ?3
+				AND deletedat IS NULL
+			)`, todo.ID, scope.UserID, scope.WorkspaceID).Scan(&exists)
+		switch {
+		case err != nil:
+			return err
+		case exists:
+			return ErrEditConflict
+		default:
+			return ErrRecordNotFound
+		}
+	}
+	todo.Tags = sortedTags(todo.Tags)
+	return setSQLiteTags(ctx, q, todo.ID, todo.Tags)
+}
+
+// Delete() moves a todo to the trash. Only the owner of a todo may delete it, and
+// only while it is still at the given version
+func (m SQLiteTodoStore) Delete(id int64, version int32, scope Scope) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sqliteDeleteTodo(ctx, m.DB, id, version, scope)
+}
+
+func sqliteDeleteTodo(ctx context.Context, q queryer, id int64, version int32, scope Scope) error {
+	query := `
+		UPDATE todos
+		SET deletedat = ?1
+		WHERE id = ?2 AND owner_id = ?3 AND workspace_id = ?4
+		AND deletedat IS NULL
+		AND version = ?5`
+
+	now := time.Now().UTC()
+	err := sqliteExpectRow(q.ExecContext(ctx, query, sqliteTimeValue(&now), id, scope.UserID, scope.WorkspaceID, version))
+	if !errors.Is(err, ErrRecordNotFound) {
+		return err
+	}
+	//a todo that is still there has moved on from the version the caller checked
+	var exists bool
+	err = q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM todos
+			WHERE id = ?1 AND owner_id = ?2 AND workspace_id = ?3
+			AND deletedat IS NULL
+		)`, id, scope.UserID, scope.WorkspaceID).Scan(&exists)
+	switch {
+	case err != nil:
+		return err
+	case exists:
+		return ErrEditConflict
+	default:
+		return ErrRecordNotFound
+	}
+}
+
+// sqliteExpectRow() turns a statement that changed no rows into ErrRecordNotFound
+func sqliteExpectRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetAll() lists the todos the user owns in their workspace
+func (m SQLiteTodoStore) GetAll(scope Scope, search TodoSearch, filters Filters) ([]*Todo, Metadata, error) {
+	var args sqliteArgs
+	conditions := []string{
+		"owner_id = " + args.add(scope.UserID),
+		"workspace_id = " + args.add(scope.WorkspaceID),
+		"deletedat IS NULL",
+	}
+	if match := ftsQuery("title", search.Title); match != "" {
+		conditions = append(conditions, "id IN (SELECT rowid FROM todos_fts WHERE todos_fts MATCH "+args.add(match)+")")
+	}
+	if match := ftsQuery("description", search.Description); match != "" {
+		conditions = append(conditions, "id IN (SELECT rowid FROM todos_fts WHERE todos_fts MATCH "+args.add(match)+")")
+	}
+	if search.Status != "" {
+		conditions = append(conditions, "status = "+args.add(search.Status))
+	}
+	if search.Priority != 0 {
+		conditions = append(conditions, "priority = "+args.add(search.Priority))
+	}
+	if search.DueBefore != nil {
+		conditions = append(conditions, "dueat < "+args.add(sqliteTimeValue(search.DueBefore)))
+	}
+	if search.DueAfter != nil {
+		conditions = append(conditions, "dueat > "+args.add(sqliteTimeValue(search.DueAfter)))
+	}
+	if search.Overdue {
+		now := time.Now()
+		conditions = append(conditions, "dueat < "+args.add(sqliteTimeValue(&now))+" AND status NOT IN ('done', 'cancelled')")
+	}
+	if len(search.Tags) > 0 {
+		placeholders := make([]string, len(search.Tags))
+		for i, tag := range search.Tags {
+			placeholders[i] = args.add(tag)
+		}
+		needed := 1
+		if search.AllTags {
+			needed = len(search.Tags)
+		}
+		conditions = append(conditions, fmt.Sprintf("(SELECT COUNT(*) FROM todo_tags WHERE todo_tags.todo_id = todos.id AND name IN (%s)) >= %s",
+			strings.Join(placeholders, ", "), args.add(needed)))
+	}
+	if search.ListID != 0 {
+		conditions = append(conditions, "FALSE")
+	}
+
+	//In cursor mode rows are picked by their position after (or before) the cursor
+	keys := filters.sortKeys()
+	order := filters.orderBy()
+	limit, offset := filters.limit(), filters.offSet()
+	var position cursor
+	if filters.UseCursor {
+		limit, offset = filters.PageSize+1, 0
+		if filters.Cursor != "" {
+			var err error
+			position, err = decodeCursor(filters.Cursor)
+			if err != nil || len(position.Values) != len(keys) {
+				return nil, Metadata{}, ErrInvalidCursor
+			}
+			placeholders := make([]string, len(keys))
+			for i, value := range position.Values {
+				placeholders[i] = args.add(value)
+			}
+			conditions = append(conditions, keysetCondition(keys, position.Before, placeholders))
+			order = orderBy(keys, position.Before)
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM todos
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s OFFSET %s`, sqliteTodoColumns, strings.Join(conditions, " AND "), order, args.add(limit), args.add(offset))
+
+	tasks, totalRecords, err := m.query(query, args)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if filters.UseCursor {
+		tasks, metadata := cursorPage(tasks, filters, keys, position)
+		return tasks, metadata, nil
+	}
+	return tasks, calculateMetaData(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// query() runs a listing selected with COUNT(*) OVER() followed by sqliteTodoColumns
+func (m SQLiteTodoStore) query(query string, args []interface{}) ([]*Todo, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tasks := []*Todo{}
+	for rows.Next() {
+		todo, err := scanSQLiteTodo(rows.Scan, &totalRecords)
+		if err != nil {
+			return nil, 0, err
+		}
+		tasks = append(tasks, todo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return tasks, totalRecords, nil
+}
+
+// Begin() starts a batch in a transaction
+func (m SQLiteTodoStore) Begin() (TodoBatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &sqliteTodoBatch{TodoTx{tx: tx, ctx: ctx, cancel: cancel}}, nil
+}
+
+// GetTrash() lists the todos the user has deleted from their workspace
+func (m SQLiteTodoStore) GetTrash(scope Scope, filters Filters) ([]*Todo, Metadata, error) {
+	var args sqliteArgs
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM todos
+		WHERE owner_id = %s AND workspace_id = %s
+		AND deletedat IS NOT NULL
+		ORDER BY %s
+		LIMIT %s OFFSET %s`, sqliteTodoColumns, args.add(scope.UserID), args.add(scope.WorkspaceID),
+		filters.orderBy(), args.add(filters.limit()), args.add(filters.offSet()))
+
+	tasks, totalRecords, err := m.query(query, args)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return tasks, calculateMetaData(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Restore() takes a todo back out of the trash
+func (m SQLiteTodoStore) Restore(id int64, scope Scope) error {
+	query := `
+		UPDATE todos
+		SET deletedat = NULL, version = version + 1
+		WHERE id = ?1 AND owner_id = ?2 AND workspace_id = ?3
+		AND deletedat IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sqliteExpectRow(m.DB.ExecContext(ctx, query, id, scope.UserID, scope.WorkspaceID))
+}
+
+// Purge() permanently removes a todo in the trash along with its tags
+func (m SQLiteTodoStore) Purge(id int64, scope Scope) error {
+	query := `
+		DELETE FROM todos
+		WHERE id = ?1 AND owner_id = ?2 AND workspace_id = ?3
+		AND deletedat IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sqliteExpectRow(m.DB.ExecContext(ctx, query, id, scope.UserID, scope.WorkspaceID))
+}
+
+// PurgeExpired() permanently removes every todo that has been in the trash for
+// longer than the retention period and reports how many were removed
+func (m SQLiteTodoStore) PurgeExpired(retention time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cutoff := time.Now().Add(-retention)
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM todos WHERE deletedat < ?1`, sqliteTimeValue(&cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// sqliteTodoBatch is the TodoBatch of SQLiteTodoStore. Savepoints, commit and
+// rollback work as they do for Postgres, only the statements differ
+type sqliteTodoBatch struct {
+	TodoTx
+}
+
+func (b *sqliteTodoBatch) Insert(todo *Todo) error {
+	return sqliteInsertTodo(b.ctx, b.tx, todo)
+}
+
+func (b *sqliteTodoBatch) Get(id int64, scope Scope) (*Todo, error) {
+	return sqliteGetTodo(b.ctx, b.tx, id, scope)
+}
+
+func (b *sqliteTodoBatch) Update(todo *Todo, scope Scope) error {
+	return sqliteUpdateTodo(b.ctx, b.tx, todo, scope)
+}
+
+func (b *sqliteTodoBatch) Delete(id int64, version int32, scope Scope) error {
+	return sqliteDeleteTodo(b.ctx, b.tx, id, version, scope)
+}
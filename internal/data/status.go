@@ -0,0 +1,45 @@
+// File: todo/internal/data/status.go
+package data
+
+// Status represents where a todo element is in its workflow
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+// The list of statuses a client is allowed to send us
+var Statuses = []string{
+	string(StatusTodo),
+	string(StatusInProgress),
+	string(StatusBlocked),
+	string(StatusDone),
+	string(StatusCancelled),
+}
+
+// The allowed transitions from one status to the next
+var statusTransitions = map[Status][]Status{
+	StatusTodo:       {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusTodo, StatusBlocked, StatusDone, StatusCancelled},
+	StatusBlocked:    {StatusTodo, StatusInProgress, StatusCancelled},
+	StatusDone:       {StatusTodo},
+	StatusCancelled:  {StatusTodo},
+}
+
+// CanTransitionTo() reports whether a todo may move from s to next.
+// Staying on the same status is always allowed
+func (s Status) CanTransitionTo(next Status) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,410 @@
+// File: todo/internal/data/store_test.go
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+	"todo.kegodo.net/internal/migrate"
+	"todo.kegodo.net/migrations"
+)
+
+// testStores opens an empty TodoStore of each kind along with the scopes of two
+// users working in two different workspaces. Postgres runs when TODOS_TEST_DSN is set
+var testStores = map[string]func(t *testing.T) (TodoStore, Scope, Scope){
+	"memory": func(t *testing.T) (TodoStore, Scope, Scope) {
+		return NewMemoryTodoStore(), Scope{UserID: 1, WorkspaceID: 1}, Scope{UserID: 2, WorkspaceID: 2}
+	},
+	"sqlite": func(t *testing.T) (TodoStore, Scope, Scope) {
+		return SQLiteTodoStore{DB: testSQLite(t)}, Scope{UserID: 1, WorkspaceID: 1}, Scope{UserID: 2, WorkspaceID: 2}
+	},
+	"postgres": func(t *testing.T) (TodoStore, Scope, Scope) {
+		models := NewModels(testPostgres(t))
+		return models.Todos, testRegister(t, models, "alice"), testRegister(t, models, "bob")
+	},
+}
+
+// testSQLite() creates a SQLite database in a temporary directory with the schema applied
+func testSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "todos.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	files, err := migrations.Files("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.New(db, "sqlite", files)
+	if err == nil {
+		err = m.Up()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestTodoStoreConformance runs the same checks against every TodoStore so that the
+// API behaves the same whichever storage it is configured with
+func TestTodoStoreConformance(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store TodoStore, a, b Scope)
+	}{
+		{name: "insert and get", run: testInsertAndGet},
+		{name: "update", run: testUpdate},
+		{name: "lists are rejected", run: testUnknownList},
+		{name: "trash", run: testTrash},
+		{name: "search and paging", run: testGetAll},
+		{name: "batch", run: testBatch},
+		{name: "workspace isolation", run: testWorkspaceIsolation},
+	}
+
+	for kind, open := range testStores {
+		t.Run(kind, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					store, a, b := open(t)
+					tt.run(t, store, a, b)
+				})
+			}
+		})
+	}
+}
+
+// newTestTodo() returns a todo for the scope with the fields a handler would fill in
+func newTestTodo(scope Scope, title string, tags ...string) *Todo {
+	return &Todo{
+		Title:       title,
+		Status:      StatusTodo,
+		Priority:    PriorityNormal,
+		Tags:        tags,
+		OwnerID:     scope.UserID,
+		WorkspaceID: scope.WorkspaceID,
+	}
+}
+
+var testFilters = Filters{Page: 1, PageSize: 20, Sort: "id", SortList: []string{"id", "-id", "title"}}
+
+func testInsertAndGet(t *testing.T, store TodoStore, a, _ Scope) {
+	due := time.Date(2030, 5, 1, 17, 30, 0, 0, time.UTC)
+	todo := newTestTodo(a, "File taxes", "money", "Admin", "deadline")
+	todo.Tags = NormalizeTags(todo.Tags)
+	todo.Description = "before the end of April"
+	todo.Priority = PriorityHigh
+	todo.DueAt = &due
+	todo.Recurrence = "FREQ=YEARLY"
+	if err := store.Insert(todo); err != nil {
+		t.Fatal(err)
+	}
+
+	if todo.ID < 1 || todo.Version != 1 || todo.CreatedAt.IsZero() || todo.Access != AccessOwner {
+		t.Errorf("Insert() set id %d, version %d, created %v, access %q", todo.ID, todo.Version, todo.CreatedAt, todo.Access)
+	}
+	if todo.CompletedAt != nil {
+		t.Errorf("open todo has completed_at %v", todo.CompletedAt)
+	}
+	if want := []string{"admin", "deadline", "money"}; !reflect.DeepEqual(todo.Tags, want) {
+		t.Errorf("Insert() echoed tags %q, want %q", todo.Tags, want)
+	}
+
+	got, err := store.Get(todo.ID, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != todo.Title || got.Description != todo.Description || got.Status != todo.Status ||
+		got.Priority != todo.Priority || got.Recurrence != todo.Recurrence || got.Version != todo.Version {
+		t.Errorf("Get() = %+v, want %+v", got, todo)
+	}
+	if !reflect.DeepEqual(got.Tags, todo.Tags) {
+		t.Errorf("Get() tags %q, Insert() echoed %q", got.Tags, todo.Tags)
+	}
+	if got.DueAt == nil || !got.DueAt.Equal(due) || got.StartsAt != nil {
+		t.Errorf("Get() dates starts %v due %v, want due %v", got.StartsAt, got.DueAt, due)
+	}
+	if !got.CreatedAt.Equal(todo.CreatedAt) {
+		t.Errorf("Get() created %v, Insert() echoed %v", got.CreatedAt, todo.CreatedAt)
+	}
+
+	done := newTestTodo(a, "Already done")
+	done.Status = StatusDone
+	if err := store.Insert(done); err != nil {
+		t.Fatal(err)
+	}
+	if done.CompletedAt == nil {
+		t.Error("todo inserted as done has no completed_at")
+	}
+
+	if _, err := store.Get(todo.ID+1000, a); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() of a missing todo = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func testUpdate(t *testing.T, store TodoStore, a, b Scope) {
+	due := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	todo := newTestTodo(a, "Paint the fence", "garden")
+	todo.DueAt = &due
+	if err := store.Insert(todo); err != nil {
+		t.Fatal(err)
+	}
+	stale := *todo
+
+	todo.Title = "Paint the fence white"
+	todo.Tags = []string{"weekend", "garden", "diy"}
+	todo.DueAt = nil
+	todo.Status = StatusDone
+	if err := store.Update(todo, a); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Version != 2 {
+		t.Errorf("Update() set version %d, want 2", todo.Version)
+	}
+	if want := []string{"diy", "garden", "weekend"}; !reflect.DeepEqual(todo.Tags, want) {
+		t.Errorf("Update() echoed tags %q, want %q", todo.Tags, want)
+	}
+	if todo.CompletedAt == nil {
+		t.Fatal("completed todo has no completed_at")
+	}
+	completedAt := *todo.CompletedAt
+
+	got, err := store.Get(todo.ID, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != todo.Title || got.DueAt != nil || !reflect.DeepEqual(got.Tags, todo.Tags) {
+		t.Errorf("Get() after Update() = %+v", got)
+	}
+
+	if err := store.Update(&stale, a); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("Update() of a stale version = %v, want ErrEditConflict", err)
+	}
+
+	//someone else's todo is missing, not in conflict, even at its current version
+	foreign := *todo
+	if err := store.Update(&foreign, b); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Update() of another user's todo = %v, want ErrRecordNotFound", err)
+	}
+
+	//completion time is kept while the todo stays done and dropped when it reopens
+	todo.Description = "two coats"
+	if err := store.Update(todo, a); err != nil {
+		t.Fatal(err)
+	}
+	if todo.CompletedAt == nil || !todo.CompletedAt.Equal(completedAt) {
+		t.Errorf("completed_at moved from %v to %v", completedAt, todo.CompletedAt)
+	}
+	todo.Status = StatusTodo
+	if err := store.Update(todo, a); err != nil {
+		t.Fatal(err)
+	}
+	if todo.CompletedAt != nil {
+		t.Errorf("reopened todo kept completed_at %v", todo.CompletedAt)
+	}
+}
+
+func testUnknownList(t *testing.T, store TodoStore, a, _ Scope) {
+	list := int64(1 << 40)
+	todo := newTestTodo(a, "On a list")
+	todo.ListID = &list
+	if err := store.Insert(todo); !errors.Is(err, ErrListNotFound) {
+		t.Errorf("Insert() on an unknown list = %v, want ErrListNotFound", err)
+	}
+
+	todo = newTestTodo(a, "Moving to a list")
+	if err := store.Insert(todo); err != nil {
+		t.Fatal(err)
+	}
+	todo.ListID = &list
+	if err := store.Update(todo, a); !errors.Is(err, ErrListNotFound) {
+		t.Errorf("Update() onto an unknown list = %v, want ErrListNotFound", err)
+	}
+}
+
+func testTrash(t *testing.T, store TodoStore, a, _ Scope) {
+	todo := newTestTodo(a, "Old idea")
+	if err := store.Insert(todo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Delete(todo.ID, todo.Version+1, a); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("Delete() at another version = %v, want ErrEditConflict", err)
+	}
+	if err := store.Delete(todo.ID, todo.Version, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(todo.ID, a); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() of a deleted todo = %v, want ErrRecordNotFound", err)
+	}
+	if err := store.Delete(todo.ID, todo.Version, a); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("second Delete() = %v, want ErrRecordNotFound", err)
+	}
+	if err := store.Purge(todo.ID+1000, a); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Purge() of a missing todo = %v, want ErrRecordNotFound", err)
+	}
+
+	trash, metadata, err := store.GetTrash(a, testFilters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].ID != todo.ID || trash[0].DeletedAt == nil || metadata.TotalRecords != 1 {
+		t.Fatalf("GetTrash() = %v %+v, want the deleted todo", trash, metadata)
+	}
+
+	//a fresh deletion is kept for the whole retention period
+	if _, err := store.PurgeExpired(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Restore(todo.ID, a); err != nil {
+		t.Fatalf("Restore() = %v", err)
+	}
+	got, err := store.Get(todo.ID, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != todo.Version+1 {
+		t.Errorf("restored todo has version %d, want %d", got.Version, todo.Version+1)
+	}
+	if err := store.Restore(todo.ID, a); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Restore() of a todo not in the trash = %v, want ErrRecordNotFound", err)
+	}
+
+	if err := store.Delete(todo.ID, got.Version, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Purge(todo.ID, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Restore(todo.ID, a); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Restore() of a purged todo = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func testGetAll(t *testing.T, store TodoStore, a, _ Scope) {
+	for _, todo := range []*Todo{
+		newTestTodo(a, "Buy oat milk", "shopping"),
+		newTestTodo(a, "Buy stamps", "shopping", "post"),
+		newTestTodo(a, "Post the letter", "post"),
+		newTestTodo(a, "Walk the dog"),
+	} {
+		if err := store.Insert(todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	titles := func(search TodoSearch, filters Filters) ([]string, Metadata) {
+		t.Helper()
+		todos, metadata, err := store.GetAll(a, search, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles := []string{}
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles, metadata
+	}
+
+	tests := []struct {
+		name   string
+		search TodoSearch
+		want   []string
+	}{
+		{name: "everything", want: []string{"Buy oat milk", "Buy stamps", "Post the letter", "Walk the dog"}},
+		{name: "title words", search: TodoSearch{Title: "buy milk"}, want: []string{"Buy oat milk"}},
+		{name: "any tag", search: TodoSearch{Tags: []string{"shopping", "post"}}, want: []string{"Buy oat milk", "Buy stamps", "Post the letter"}},
+		{name: "all tags", search: TodoSearch{Tags: []string{"shopping", "post"}, AllTags: true}, want: []string{"Buy stamps"}},
+		{name: "status", search: TodoSearch{Status: string(StatusDone)}, want: []string{}},
+	}
+	for _, tt := range tests {
+		if got, _ := titles(tt.search, testFilters); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: GetAll() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	page := testFilters
+	page.Sort, page.Page, page.PageSize = "-id", 2, 3
+	got, metadata := titles(TodoSearch{}, page)
+	if want := []string{"Buy oat milk"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second page = %q, want %q", got, want)
+	}
+	if want := (Metadata{CurrentPage: 2, PageSize: 3, FirstPage: 1, LastPage: 2, TotalRecords: 4}); metadata != want {
+		t.Errorf("metadata = %+v, want %+v", metadata, want)
+	}
+}
+
+func testBatch(t *testing.T, store TodoStore, a, _ Scope) {
+	count := func() int {
+		t.Helper()
+		todos, _, err := store.GetAll(a, TodoSearch{}, testFilters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(todos)
+	}
+
+	//a rolled back batch leaves nothing behind
+	tx, err := store.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Insert(newTestTodo(a, "Discarded")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("rolled back batch left %d todos", n)
+	}
+
+	//a step undone with its savepoint is dropped while the others are kept
+	tx, err = store.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	kept := newTestTodo(a, "Kept", "b", "a")
+	if err := tx.Insert(kept); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Savepoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Insert(newTestTodo(a, "Undone")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RollbackToSavepoint(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tx.Get(kept.ID, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Title = "Kept and changed"
+	if err := tx.Update(got, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("Rollback() after Commit() = %v", err)
+	}
+
+	todos, _, err := store.GetAll(a, TodoSearch{}, testFilters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0].Title != "Kept and changed" || !reflect.DeepEqual(todos[0].Tags, []string{"a", "b"}) {
+		t.Errorf("committed batch left %+v", todos)
+	}
+}
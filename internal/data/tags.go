@@ -0,0 +1,237 @@
+// File: todo/internal/data/tags.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"todo.kegodo.net/internal/validator"
+)
+
+var ErrDuplicateTag = errors.New("duplicate tag")
+
+// Tag struct supports the information for a label that can be attached to todos
+type Tag struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Todos     int       `json:"todos"`
+}
+
+// NormalizeTags() trims and lowercases tag names so "Backend" and "backend " are the same tag
+func NormalizeTags(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(name)))
+	}
+	return normalized
+}
+
+// sortedTags() returns a sorted copy of tag names. Every store hands tags back in
+// this order, the same order the queries read them in
+func sortedTags(tags []string) []string {
+	sorted := append([]string{}, tags...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func ValidateTagName(v *validator.Validator, key string, name string) {
+	v.Check(name != "", key, "must not contain empty tag names")
+	v.Check(len(name) <= 50, key, "must not contain tag names more than 50 bytes long")
+}
+
+func ValidateTags(v *validator.Validator, tags []string) {
+	v.Check(len(tags) <= 10, "tags", "must not contain more than 10 tags")
+	v.Check(validator.Unique(tags), "tags", "must not contain duplicate values")
+	for _, tag := range tags {
+		ValidateTagName(v, "tags", tag)
+	}
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// GetAll() returns every tag of a workspace along with the number of todos carrying
+// it. Todos in the trash are not counted
+func (m TagModel) GetAll(workspaceID int64) ([]*Tag, error) {
+	query := `
+		SELECT tags.id, tags.createdat, tags.name, COUNT(todos.id)
+		FROM tags
+		LEFT JOIN todo_tags ON todo_tags.tag_id = tags.id
+		LEFT JOIN todos ON todos.id = todo_tags.todo_id AND todos.deletedat IS NULL
+		WHERE tags.workspace_id = $1
+		GROUP BY tags.id
+		ORDER BY tags.name ASC
+	`
+
+	//creating the 3 second time out context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(&tag.ID, &tag.CreatedAt, &tag.Name, &tag.Todos)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Get() retrieves a specific tag of a workspace
+func (m TagModel) Get(id int64, workspaceID int64) (*Tag, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT tags.id, tags.createdat, tags.name,
+		       (SELECT COUNT(*) FROM todo_tags JOIN todos ON todos.id = todo_tags.todo_id
+		        WHERE todo_tags.tag_id = tags.id AND todos.deletedat IS NULL)
+		FROM tags
+		WHERE tags.id = $1
+		AND tags.workspace_id = $2
+	`
+
+	var tag Tag
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, workspaceID).Scan(&tag.ID, &tag.CreatedAt, &tag.Name, &tag.Todos)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &tag, nil
+}
+
+// Rename() changes the name of a tag on every todo of the workspace that carries it
+func (m TagModel) Rename(tag *Tag, workspaceID int64) error {
+	query := `
+		UPDATE tags
+		SET name = $1
+		WHERE id = $2 AND workspace_id = $3
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, tag.Name, tag.ID, workspaceID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "tags_workspace_id_name_key"`:
+			return ErrDuplicateTag
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Merge() moves every todo tagged with the source tag over to the target tag
+// and then removes the source tag. Both tags must belong to the workspace
+func (m TagModel) Merge(sourceID, targetID int64, workspaceID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	query := `SELECT COUNT(*) FROM tags WHERE id IN ($1, $2) AND workspace_id = $3`
+	err = tx.QueryRowContext(ctx, query, sourceID, targetID, workspaceID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return ErrRecordNotFound
+	}
+
+	//Todos already carrying the target tag keep a single link
+	query = `
+		INSERT INTO todo_tags (todo_id, tag_id)
+		SELECT todo_id, $2 FROM todo_tags WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+	`
+	_, err = tx.ExecContext(ctx, query, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+
+	//Removing the source tag cascades to its links
+	result, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, sourceID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// setTodoTags() replaces the tags attached to a todo, creating any tags that do not
+// exist in its workspace yet
+func setTodoTags(ctx context.Context, tx *sql.Tx, todoID int64, workspaceID int64, names []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM todo_tags WHERE todo_id = $1`, todoID)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO tags (name, workspace_id)
+		SELECT unnest($1::text[]), $2
+		ON CONFLICT (workspace_id, name) DO NOTHING
+	`
+	_, err = tx.ExecContext(ctx, query, pq.Array(names), workspaceID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO todo_tags (todo_id, tag_id)
+		SELECT $1, id FROM tags WHERE workspace_id = $2 AND name = ANY($3)
+	`
+	_, err = tx.ExecContext(ctx, query, todoID, workspaceID, pq.Array(names))
+	return err
+}
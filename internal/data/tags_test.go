@@ -0,0 +1,36 @@
+// File: todo/internal/data/tags_test.go
+package data
+
+import "testing"
+
+func TestTagCountsSkipTrash(t *testing.T) {
+	models := NewModels(testPostgres(t))
+	scope := testRegister(t, models, "alice")
+
+	var todos []*Todo
+	for _, title := range []string{"Read a book", "Read the paper"} {
+		todo := newTestTodo(scope, title, "reading")
+		if err := models.Todos.Insert(todo); err != nil {
+			t.Fatal(err)
+		}
+		todos = append(todos, todo)
+	}
+	if err := models.Todos.Delete(todos[1].ID, todos[1].Version, scope); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := models.Tags.GetAll(scope.WorkspaceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Todos != 1 {
+		t.Fatalf("GetAll() = %+v, want reading on one todo", tags)
+	}
+	tag, err := models.Tags.Get(tags[0].ID, scope.WorkspaceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.Todos != 1 {
+		t.Errorf("Get() counts %d todos, want 1", tag.Todos)
+	}
+}
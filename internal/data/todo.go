@@ -6,19 +6,35 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"todo.kegodo.net/internal/validator"
 )
 
 // Todo struct supports the infromation for the todo todo
 type Todo struct {
-	ID          int64     `json:"id"`
-	CreatedAt   time.Time `json:"-"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Done        string    `json:"done"`
-	Version     int32     `json:"version"`
+	ID          int64      `json:"id"`
+	CreatedAt   time.Time  `json:"-"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      Status     `json:"status"`
+	Priority    Priority   `json:"priority"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Tags        []string   `json:"tags"`
+	Checklist   Checklist  `json:"checklist"`
+	ListID      *int64     `json:"list_id,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	NextID      *int64     `json:"next_id,omitempty"`
+	OwnerID     int64      `json:"owner_id,omitempty"`
+	WorkspaceID int64      `json:"workspace_id,omitempty"`
+	Access      Access     `json:"access,omitempty"`
+	Version     int32      `json:"version"`
 }
 
 func ValidateTodo(v *validator.Validator, todo *Todo) {
@@ -28,59 +44,175 @@ func ValidateTodo(v *validator.Validator, todo *Todo) {
 
 	v.Check(len(todo.Description) <= 250, "description", "must no be more than 250 bytes long")
 
+	v.Check(validator.In(string(todo.Status), Statuses...), "status", "must be one of todo, in_progress, blocked, done or cancelled")
+
+	v.Check(todo.Priority.Valid(), "priority", "must be one of low, normal, high or urgent")
+
+	ValidateTags(v, todo.Tags)
+	ValidateRecurrence(v, todo)
+
+	if todo.ListID != nil {
+		v.Check(*todo.ListID > 0, "list_id", "must be a positive integer")
+	}
+
+	//a todo cannot be due before it starts
+	if todo.StartsAt != nil && todo.DueAt != nil {
+		v.Check(!todo.DueAt.Before(*todo.StartsAt), "due_at", "must not be before starts_at")
+	}
+}
+
+// TodoSearch holds the criteria used to narrow down a listing of todo elements
+type TodoSearch struct {
+	Title       string
+	Description string
+	Status      string
+	Priority    Priority
+	DueBefore   *time.Time
+	DueAfter    *time.Time
+	Overdue     bool
+	Tags        []string
+	AllTags     bool
+	ListID      int64
+}
+
+// ValidateStatusTransition() checks that a todo is allowed to move from one status to another
+func ValidateStatusTransition(v *validator.Validator, from, to Status) {
+	//unknown statuses are reported by ValidateTodo()
+	if !validator.In(string(to), Statuses...) {
+		return
+	}
+	v.Check(from.CanTransitionTo(to), "status", fmt.Sprintf("cannot move from %s to %s", from, to))
+}
+
+// TodoStore is the storage behind todo elements. TodoModel keeps them in Postgres
+// and MemoryTodoStore keeps them in memory for the demo mode and for tests
+type TodoStore interface {
+	Insert(todo *Todo) error
+	Get(id int64, scope Scope) (*Todo, error)
+	Update(todo *Todo, scope Scope) error
+	Delete(id int64, version int32, scope Scope) error
+	GetAll(scope Scope, search TodoSearch, filters Filters) ([]*Todo, Metadata, error)
+	Begin() (TodoBatch, error)
+	GetTrash(scope Scope, filters Filters) ([]*Todo, Metadata, error)
+	Restore(id int64, scope Scope) error
+	Purge(id int64, scope Scope) error
+	PurgeExpired(retention time.Duration) (int64, error)
 }
 
 type TodoModel struct {
 	DB *sql.DB
 }
 
+// queryer is satisfied by both *sql.DB and *sql.Tx so the same statements can run
+// on their own or as one step of a larger transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // Insert() allows us to create a new todo
 func (m TodoModel) Insert(todo *Todo) error {
-	query := `
-		INSERT INTO todos (title, description, done)
-		VALUES ($1, $2, $3)
-		RETURNING id, createdat, version
-	`
-
 	//creating the context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	//Clean up to prevent memory leaks
 	defer cancel()
 
+	//the todo and its tags are written together
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertTodo(ctx, tx, todo)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertTodo(ctx context.Context, tx *sql.Tx, todo *Todo) error {
+	query := `
+		INSERT INTO todos (title, description, status, completedat, startsat, dueat, priority, list_id, recurrence, owner_id, workspace_id)
+		VALUES ($1, $2, $3, CASE WHEN $3 = 'done' THEN NOW() END, $4, $5, $6, $7, $8, NULLIF($9, 0), $10)
+		RETURNING id, createdat, completedat, version
+	`
+
 	//collect the date field into a slice
-	args := []interface{}{todo.Title, todo.Description, todo.Done}
+	args := []interface{}{todo.Title, todo.Description, todo.Status, todo.StartsAt, todo.DueAt, todo.Priority, todo.ListID, todo.Recurrence, todo.OwnerID, todo.WorkspaceID}
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&todo.ID, &todo.CreatedAt, &todo.CompletedAt, &todo.Version)
+	if err != nil {
+		return listError(err)
+	}
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&todo.ID, &todo.CreatedAt, &todo.Version)
+	err = setTodoTags(ctx, tx, todo.ID, todo.WorkspaceID, todo.Tags)
+	if err != nil {
+		return err
+	}
+	todo.Tags = sortedTags(todo.Tags)
+	err = recordRevision(ctx, tx, todo.ID, todo.Version, RevisionCreate, todo.OwnerID, nil, todo.snapshot())
+	if err != nil {
+		return err
+	}
+	todo.Access = AccessOwner
+	return nil
 }
 
-// Get() allows us to retrieve a specific task
-func (m TodoModel) Get(id int64) (*Todo, error) {
+// Get() allows us to retrieve a specific task the user is allowed to see in their workspace
+func (m TodoModel) Get(id int64, scope Scope) (*Todo, error) {
+	//Creating the context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	//Cleaning up to prevent memory leaks
+	defer cancel()
+
+	return getTodo(ctx, m.DB, id, scope)
+}
+
+func getTodo(ctx context.Context, q queryer, id int64, scope Scope) (*Todo, error) {
 	//Ensure that there is a valid id
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	//Construct our query with the given id
-	query := `
-		SELECT id, createdat, title, description, done, version
+	query := fmt.Sprintf(`
+		SELECT id, createdat, title, description, status, priority, completedat, startsat, dueat, list_id, recurrence, next_id,
+		       COALESCE(owner_id, 0), workspace_id, %s,
+		       ARRAY(SELECT tags.name FROM tags JOIN todo_tags ON todo_tags.tag_id = tags.id
+		             WHERE todo_tags.todo_id = todos.id ORDER BY tags.name),
+		       (SELECT COUNT(*) FILTER (WHERE done) FROM todo_items WHERE todo_items.todo_id = todos.id),
+		       (SELECT COUNT(*) FROM todo_items WHERE todo_items.todo_id = todos.id),
+		       version
 		FROM todos
 		WHERE id = $1
-	`
+		AND workspace_id = $3
+		AND deletedat IS NULL
+		AND %s`, accessColumn("todos", "$2", todoEditAccess), fmt.Sprintf(todoViewAccess, "$2"))
 
 	//Declaring the Todo varaible to hold the returned data
 	var todo Todo
 
-	//Creating the context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	//Cleaning up to prevent memory leaks
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+	err := q.QueryRowContext(ctx, query, id, scope.UserID, scope.WorkspaceID).Scan(
 		&todo.ID,
 		&todo.CreatedAt,
 		&todo.Title,
 		&todo.Description,
-		&todo.Done,
+		&todo.Status,
+		&todo.Priority,
+		&todo.CompletedAt,
+		&todo.StartsAt,
+		&todo.DueAt,
+		&todo.ListID,
+		&todo.Recurrence,
+		&todo.NextID,
+		&todo.OwnerID,
+		&todo.WorkspaceID,
+		&todo.Access,
+		pq.Array(&todo.Tags),
+		&todo.Checklist.Done,
+		&todo.Checklist.Total,
 		&todo.Version,
 	)
 
@@ -97,87 +229,252 @@ func (m TodoModel) Get(id int64) (*Todo, error) {
 	return &todo, nil
 }
 
-// Update() allows us to edit/alter a specific todo task
+// Update() allows us to edit/alter a specific todo task the user is allowed to change
 // Optimistic locking (version number)
-func (m TodoModel) Update(todo *Todo) error {
+func (m TodoModel) Update(todo *Todo, scope Scope) error {
+	//Creating the context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	//Cleaning up to prevent memory leaks
+	defer cancel()
+
+	//the todo and its tags are written together
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateTodo(ctx, tx, todo, scope)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func updateTodo(ctx context.Context, tx *sql.Tx, todo *Todo, scope Scope) error {
 	//create a query
 	query := `
 		UPDATE todos
-		SET title = $1, description = $2, done = $3, version = version + 1
-		WHERE id = $4
-		RETURNING version
+		SET title = $1, description = $2, status = $3,
+		    completedat = CASE WHEN $3 = 'done' THEN COALESCE(completedat, NOW()) END,
+		    startsat = $4, dueat = $5, priority = $6, list_id = $7, recurrence = $8,
+		    next_id = $13, version = version + 1
+		WHERE id = $9
+		AND version = $12
+		AND workspace_id = $11
+		AND deletedat IS NULL
+		AND ` + fmt.Sprintf(todoEditAccess, "$10") + `
+		RETURNING completedat, version
 	`
 	args := []interface{}{
 		todo.Title,
 		todo.Description,
-		todo.Done,
+		todo.Status,
+		todo.StartsAt,
+		todo.DueAt,
+		todo.Priority,
+		todo.ListID,
+		todo.Recurrence,
 		todo.ID,
+		scope.UserID,
+		scope.WorkspaceID,
+		todo.Version,
+		todo.NextID,
 	}
 
-	//Creating the context
+	//the history records the todo as it was before the change
+	before, err := loadSnapshot(ctx, tx, todo.ID)
+	if err != nil {
+		return err
+	}
+
+	//Check for edit conflicts; no row for a todo the user can still edit means
+	//the version we read has since been replaced by someone else's write
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&todo.CompletedAt, &todo.Version)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return listError(err)
+		}
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM todos
+				WHERE id = $1 AND workspace_id = $3
+				AND deletedat IS NULL
+				AND `+fmt.Sprintf(todoEditAccess, "$2")+`
+			)`, todo.ID, scope.UserID, scope.WorkspaceID).Scan(&exists)
+		switch {
+		case err != nil:
+			return err
+		case exists:
+			return ErrEditConflict
+		default:
+			return ErrRecordNotFound
+		}
+	}
+
+	err = setTodoTags(ctx, tx, todo.ID, scope.WorkspaceID, todo.Tags)
+	if err != nil {
+		return err
+	}
+	todo.Tags = sortedTags(todo.Tags)
+	return recordRevision(ctx, tx, todo.ID, todo.Version, RevisionUpdate, scope.UserID, before, todo.snapshot())
+}
+
+// Delete() moves a todo to the trash, from where it can be restored until it is
+// purged. Only the owner of a todo may delete it, and only while it is still at
+// the given version
+func (m TodoModel) Delete(id int64, version int32, scope Scope) error {
+	//creating the context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	//Cleaning up to prevent memory leaks
+	//clearing up to prevent memory leaks
 	defer cancel()
 
-	//Check for edit conflicts
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&todo.Version)
+	//the todo and its history are written together
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	err = deleteTodo(ctx, tx, id, version, scope)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m TodoModel) Delete(id int64) error {
+func deleteTodo(ctx context.Context, tx *sql.Tx, id int64, version int32, scope Scope) error {
 	//Ensure that there is a valid id
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 	//Create the delete query
 	query := `
-		DELETE FROM todos
-		WHERE id = $1
+		UPDATE todos
+		SET deletedat = NOW()
+		WHERE id = $1 AND owner_id = $2 AND workspace_id = $3
+		AND deletedat IS NULL
+		AND version = $4
 	`
 
-	//creating the context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	//clearing up to prevent memory leaks
-	defer cancel()
-
-	//Execute the query
-	result, err := m.DB.ExecContext(ctx, query, id)
+	//Execute the query, no row means there was nothing the user could delete
+	//or the todo has moved on from the version the caller checked
+	result, err := tx.ExecContext(ctx, query, id, scope.UserID, scope.WorkspaceID, version)
 	if err != nil {
 		return err
 	}
-
-	//Check how many rows were affected by the delete operation.
-	//We call the RowsAffected() method on the result variable
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-
-	//Check if no rows were affected
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM todos
+				WHERE id = $1 AND owner_id = $2 AND workspace_id = $3
+				AND deletedat IS NULL
+			)`, id, scope.UserID, scope.WorkspaceID).Scan(&exists)
+		switch {
+		case err != nil:
+			return err
+		case exists:
+			return ErrEditConflict
+		default:
+			return ErrRecordNotFound
+		}
 	}
-	return nil
+
+	snapshot, err := loadSnapshot(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	return recordRevision(ctx, tx, id, version, RevisionDelete, scope.UserID, snapshot, *snapshot)
 }
 
-func (m TodoModel) GetAll(title string, description string, done string, filters Filters) ([]*Todo, Metadata, error) {
+// GetAll() lists the todos the user is allowed to see in their workspace
+func (m TodoModel) GetAll(scope Scope, search TodoSearch, filters Filters) ([]*Todo, Metadata, error) {
+	//In cursor mode rows are picked by their position after (or before) the cursor
+	//instead of by offset, and one extra row tells us whether more follow
+	keys := filters.sortKeys()
+	keyset, order := "TRUE", filters.orderBy()
+	limit, offset := filters.limit(), filters.offSet()
+	var position cursor
+	if filters.UseCursor {
+		limit, offset = filters.PageSize+1, 0
+		if filters.Cursor != "" {
+			var err error
+			position, err = decodeCursor(filters.Cursor)
+			if err != nil || len(position.Values) != len(keys) {
+				return nil, Metadata{}, ErrInvalidCursor
+			}
+			placeholders := make([]string, len(keys))
+			for i, key := range keys {
+				placeholders[i] = fmt.Sprintf("$%d::%s", 15+i, cursorTypes[key.column])
+			}
+			keyset = keysetCondition(keys, position.Before, placeholders)
+			order = orderBy(keys, position.Before)
+		}
+	}
+
 	//constructing the query
 	query := fmt.Sprintf(`
 		SELECT COUNT(*) OVER(),
-	    id, createdat, title, description, done, version
+	    id, createdat, title, description, status, priority, completedat, startsat, dueat, list_id, recurrence, next_id,
+		COALESCE(owner_id, 0), workspace_id, %s,
+		ARRAY(SELECT tags.name FROM tags JOIN todo_tags ON todo_tags.tag_id = tags.id
+		             WHERE todo_tags.todo_id = todos.id ORDER BY tags.name),
+		(SELECT COUNT(*) FILTER (WHERE done) FROM todo_items WHERE todo_items.todo_id = todos.id),
+		(SELECT COUNT(*) FROM todo_items WHERE todo_items.todo_id = todos.id),
+		version
 		FROM todos
 		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		AND (to_tsvector('simple', description) @@ plainto_tsquery('simple', $2) OR $2 = '')
-		AND to_tsvector('simple', done) @@ plainto_tsquery('simple', $3) OR $3 = '')
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortOrder())
+		AND (status = $3 OR $3 = '')
+		AND (priority = $4 OR $4 = 0)
+		AND (dueat < $5 OR $5 IS NULL)
+		AND (dueat > $6 OR $6 IS NULL)
+		AND (NOT $7 OR (dueat < NOW() AND status NOT IN ('done', 'cancelled')))
+		AND (cardinality($8::text[]) = 0 OR (
+			SELECT COUNT(*) FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id
+			WHERE todo_tags.todo_id = todos.id AND tags.name = ANY($8)
+		) >= CASE WHEN $9 THEN cardinality($8::text[]) ELSE 1 END)
+		AND (list_id = $10 OR $10 = 0)
+		AND workspace_id = $12
+		AND deletedat IS NULL
+		AND %s
+		AND %s
+		ORDER BY %s
+		LIMIT $13 OFFSET $14`,
+		accessColumn("todos", "$11", todoEditAccess), fmt.Sprintf(todoViewAccess, "$11"), keyset, order)
 
 	//creating the 3 second time out context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	//Execute the query
-	args := []interface{}{title, description, filters.limit(), filters.offSet()}
+	args := []interface{}{
+		search.Title,
+		search.Description,
+		search.Status,
+		search.Priority,
+		search.DueBefore,
+		search.DueAfter,
+		search.Overdue,
+		pq.Array(search.Tags),
+		search.AllTags,
+		search.ListID,
+		scope.UserID,
+		scope.WorkspaceID,
+		limit,
+		offset,
+	}
+	if keyset != "TRUE" {
+		for _, value := range position.Values {
+			args = append(args, value)
+		}
+	}
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
@@ -199,8 +496,22 @@ func (m TodoModel) GetAll(title string, description string, done string, filters
 			&totalRecords,
 			&todo.ID,
 			&todo.CreatedAt,
+			&todo.Title,
 			&todo.Description,
-			&todo.Done,
+			&todo.Status,
+			&todo.Priority,
+			&todo.CompletedAt,
+			&todo.StartsAt,
+			&todo.DueAt,
+			&todo.ListID,
+			&todo.Recurrence,
+			&todo.NextID,
+			&todo.OwnerID,
+			&todo.WorkspaceID,
+			&todo.Access,
+			pq.Array(&todo.Tags),
+			&todo.Checklist.Done,
+			&todo.Checklist.Total,
 			&todo.Version,
 		)
 		if err != nil {
@@ -213,7 +524,165 @@ func (m TodoModel) GetAll(title string, description string, done string, filters
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
+	if filters.UseCursor {
+		tasks, metadata := cursorPage(tasks, filters, keys, position)
+		return tasks, metadata, nil
+	}
 	metadata := calculateMetaData(totalRecords, filters.Page, filters.PageSize)
 	//returning the slice of todos
 	return tasks, metadata, nil
 }
+
+// cursorPage() trims the extra row fetched in cursor mode, puts a backwards page
+// back into display order and works out the cursors for the neighbouring pages
+func cursorPage(tasks []*Todo, filters Filters, keys []sortKey, position cursor) ([]*Todo, Metadata) {
+	more := len(tasks) > filters.PageSize
+	if more {
+		tasks = tasks[:filters.PageSize]
+	}
+	if position.Before {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	}
+
+	metadata := Metadata{PageSize: filters.PageSize}
+	at := func(todo *Todo, before bool) string {
+		c := cursor{Sort: filters.Sort, Before: before}
+		for _, key := range keys {
+			c.Values = append(c.Values, todo.sortValue(key.column))
+		}
+		return encodeCursor(c)
+	}
+	switch {
+	case len(tasks) == 0 && filters.Cursor != "":
+		//Nothing on this side of the cursor, but the other side can still be reached
+		position.Before = !position.Before
+		if position.Before {
+			metadata.PrevCursor = encodeCursor(position)
+		} else {
+			metadata.NextCursor = encodeCursor(position)
+		}
+	case len(tasks) == 0:
+	case position.Before:
+		metadata.NextCursor = at(tasks[len(tasks)-1], false)
+		if more {
+			metadata.PrevCursor = at(tasks[0], true)
+		}
+	default:
+		if more {
+			metadata.NextCursor = at(tasks[len(tasks)-1], false)
+		}
+		if filters.Cursor != "" {
+			metadata.PrevCursor = at(tasks[0], true)
+		}
+	}
+	return tasks, metadata
+}
+
+// sortValue() returns the todo's value in a sort column as the text stored in a cursor
+func (todo *Todo) sortValue(column string) *string {
+	var value string
+	switch column {
+	case "id":
+		value = strconv.FormatInt(todo.ID, 10)
+	case "title":
+		value = todo.Title
+	case "description":
+		value = todo.Description
+	case "status":
+		value = string(todo.Status)
+	case "priority":
+		value = strconv.Itoa(int(todo.Priority))
+	case "dueat", "startsat":
+		t := todo.DueAt
+		if column == "startsat" {
+			t = todo.StartsAt
+		}
+		if t == nil {
+			return nil
+		}
+		value = t.Format(time.RFC3339Nano)
+	}
+	return &value
+}
+
+// listError() reports a todo pointing at a list that does not exist as ErrListNotFound
+func listError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `violates foreign key constraint "todos_list_id_fkey"`):
+		return ErrListNotFound
+	default:
+		return err
+	}
+}
+
+// Claim() hands the todos and lists created before there were user accounts, which
+// have no owner, to the user in the scope and moves them and their tags into its
+// workspace. It reports how many todos were claimed
+func (m TodoModel) Claim(scope Scope) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE todos
+		SET owner_id = $1, workspace_id = $2, version = version + 1
+		WHERE owner_id IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, scope.UserID, scope.WorkspaceID)
+	if err != nil {
+		return 0, err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		UPDATE lists
+		SET owner_id = $1, workspace_id = $2
+		WHERE owner_id IS NULL
+	`
+	_, err = tx.ExecContext(ctx, query, scope.UserID, scope.WorkspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	//tags without a workspace only label unclaimed todos, the workspace gets its
+	//own tag of the same name in their place
+	query = `
+		INSERT INTO tags (name, workspace_id)
+		SELECT name, $1 FROM tags WHERE workspace_id IS NULL
+		ON CONFLICT (workspace_id, name) DO NOTHING
+	`
+	_, err = tx.ExecContext(ctx, query, scope.WorkspaceID)
+	if err != nil {
+		return 0, err
+	}
+	query = `
+		UPDATE todo_tags SET tag_id = copies.id
+		FROM tags, tags copies
+		WHERE tags.id = todo_tags.tag_id AND tags.workspace_id IS NULL
+		AND copies.workspace_id = $1 AND copies.name = tags.name
+	`
+	_, err = tx.ExecContext(ctx, query, scope.WorkspaceID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM tags WHERE workspace_id IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return claimed, nil
+}
@@ -0,0 +1,164 @@
+// File: todo/internal/data/trash.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetTrash() lists the todos the user has deleted from their workspace, most recently deleted first
+func (m TodoModel) GetTrash(scope Scope, filters Filters) ([]*Todo, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(),
+		       id, createdat, title, description, status, priority, completedat, startsat, dueat, deletedat,
+		       list_id, recurrence, next_id, owner_id, workspace_id,
+		       ARRAY(SELECT tags.name FROM tags JOIN todo_tags ON todo_tags.tag_id = tags.id
+		             WHERE todo_tags.todo_id = todos.id ORDER BY tags.name),
+		       version
+		FROM todos
+		WHERE owner_id = $1
+		AND workspace_id = $2
+		AND deletedat IS NOT NULL
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, filters.orderBy())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, scope.UserID, scope.WorkspaceID, filters.limit(), filters.offSet())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tasks := []*Todo{}
+	for rows.Next() {
+		todo := Todo{Access: AccessOwner}
+		err := rows.Scan(
+			&totalRecords,
+			&todo.ID,
+			&todo.CreatedAt,
+			&todo.Title,
+			&todo.Description,
+			&todo.Status,
+			&todo.Priority,
+			&todo.CompletedAt,
+			&todo.StartsAt,
+			&todo.DueAt,
+			&todo.DeletedAt,
+			&todo.ListID,
+			&todo.Recurrence,
+			&todo.NextID,
+			&todo.OwnerID,
+			&todo.WorkspaceID,
+			pq.Array(&todo.Tags),
+			&todo.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		tasks = append(tasks, &todo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetaData(totalRecords, filters.Page, filters.PageSize)
+	return tasks, metadata, nil
+}
+
+// Restore() takes a todo back out of the trash
+func (m TodoModel) Restore(id int64, scope Scope) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+		UPDATE todos
+		SET deletedat = NULL, version = version + 1
+		WHERE id = $1 AND owner_id = $2 AND workspace_id = $3
+		AND deletedat IS NOT NULL
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	//the todo and its history are written together
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int32
+	err = tx.QueryRowContext(ctx, query, id, scope.UserID, scope.WorkspaceID).Scan(&version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	snapshot, err := loadSnapshot(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	err = recordRevision(ctx, tx, id, version, RevisionRestore, scope.UserID, snapshot, *snapshot)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Purge() permanently removes a todo in the trash along with its checklist items and tag links
+func (m TodoModel) Purge(id int64, scope Scope) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+		DELETE FROM todos
+		WHERE id = $1 AND owner_id = $2 AND workspace_id = $3
+		AND deletedat IS NOT NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, scope.UserID, scope.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeExpired() permanently removes every todo that has been in the trash for
+// longer than the retention period and reports how many were removed
+func (m TodoModel) PurgeExpired(retention time.Duration) (int64, error) {
+	query := `
+		DELETE FROM todos
+		WHERE deletedat < $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,248 @@
+// File: todo/internal/data/workspaces.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"todo.kegodo.net/internal/validator"
+)
+
+// The roles a member can hold in a workspace
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+)
+
+// Workspace struct supports the information for a tenant whose todos are kept apart from every other
+type Workspace struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	Version   int32     `json:"version"`
+}
+
+// Member struct supports the information for a user belonging to a workspace
+type Member struct {
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Scope names who is asking and which workspace they are working in. Every
+// todo query is limited to the workspace of its scope
+type Scope struct {
+	UserID      int64
+	WorkspaceID int64
+}
+
+func ValidateWorkspace(v *validator.Validator, workspace *Workspace) {
+	v.Check(workspace.Name != "", "name", "must be provided")
+	v.Check(len(workspace.Name) <= 100, "name", "must not be more than 100 bytes long")
+}
+
+func ValidateRole(v *validator.Validator, role string) {
+	v.Check(validator.In(role, RoleOwner, RoleMember), "role", "must be either owner or member")
+}
+
+type WorkspaceModel struct {
+	DB *sql.DB
+}
+
+// Insert() creates a workspace and makes the user its owner
+func (m WorkspaceModel) Insert(workspace *Workspace, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertWorkspace(ctx, tx, workspace, ownerID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertWorkspace() writes the workspace and its owner; q should be a transaction
+func insertWorkspace(ctx context.Context, q queryer, workspace *Workspace, ownerID int64) error {
+	query := `
+		INSERT INTO workspaces (name)
+		VALUES ($1)
+		RETURNING id, createdat, version
+	`
+	err := q.QueryRowContext(ctx, query, workspace.Name).Scan(&workspace.ID, &workspace.CreatedAt, &workspace.Version)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`
+	_, err = q.ExecContext(ctx, query, workspace.ID, ownerID, RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	workspace.Role = RoleOwner
+	return nil
+}
+
+// GetForUser() retrieves a workspace the user is a member of. An id of zero
+// picks the user's oldest workspace, which is the one created when they registered
+func (m WorkspaceModel) GetForUser(id int64, userID int64) (*Workspace, error) {
+	query := `
+		SELECT workspaces.id, workspaces.createdat, workspaces.name, workspace_members.role, workspaces.version
+		FROM workspaces
+		INNER JOIN workspace_members ON workspace_members.workspace_id = workspaces.id
+		WHERE workspace_members.user_id = $2
+		AND (workspaces.id = $1 OR $1 = 0)
+		ORDER BY workspaces.id ASC
+		LIMIT 1
+	`
+
+	var workspace Workspace
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&workspace.ID,
+		&workspace.CreatedAt,
+		&workspace.Name,
+		&workspace.Role,
+		&workspace.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &workspace, nil
+}
+
+// GetAllForUser() lists every workspace the user is a member of
+func (m WorkspaceModel) GetAllForUser(userID int64) ([]*Workspace, error) {
+	query := `
+		SELECT workspaces.id, workspaces.createdat, workspaces.name, workspace_members.role, workspaces.version
+		FROM workspaces
+		INNER JOIN workspace_members ON workspace_members.workspace_id = workspaces.id
+		WHERE workspace_members.user_id = $1
+		ORDER BY workspaces.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []*Workspace{}
+	for rows.Next() {
+		var workspace Workspace
+		err := rows.Scan(
+			&workspace.ID,
+			&workspace.CreatedAt,
+			&workspace.Name,
+			&workspace.Role,
+			&workspace.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, &workspace)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// GetMembers() lists the members of a workspace
+func (m WorkspaceModel) GetMembers(workspaceID int64) ([]*Member, error) {
+	query := `
+		SELECT users.id, users.name, users.email, workspace_members.role, workspace_members.createdat
+		FROM workspace_members
+		INNER JOIN users ON users.id = workspace_members.user_id
+		WHERE workspace_members.workspace_id = $1
+		ORDER BY users.name ASC, users.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		var member Member
+		err := rows.Scan(&member.UserID, &member.Name, &member.Email, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMember() adds a user to a workspace, changing the role of an existing member
+func (m WorkspaceModel) AddMember(workspaceID int64, member *Member) error {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING createdat
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, workspaceID, member.UserID, member.Role).Scan(&member.CreatedAt)
+}
+
+// RemoveMember() takes a user out of a workspace
+func (m WorkspaceModel) RemoveMember(workspaceID int64, userID int64) error {
+	query := `
+		DELETE FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// File: todo/internal/data/workspaces_test.go
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+// testWorkspaceIsolation() checks that a store never lets a scope read, change or
+// delete a todo of another workspace, even one owned by the same user. It is part
+// of the conformance suite in store_test.go
+func testWorkspaceIsolation(t *testing.T, store TodoStore, a, b Scope) {
+	t.Helper()
+	insert := func(scope Scope, title string) *Todo {
+		todo := &Todo{
+			Title:       title,
+			Status:      StatusTodo,
+			Priority:    PriorityNormal,
+			Tags:        []string{"errands"},
+			OwnerID:     scope.UserID,
+			WorkspaceID: scope.WorkspaceID,
+		}
+		if err := store.Insert(todo); err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	mine := insert(a, "Buy milk")
+	theirs := insert(b, "Buy bread")
+	//the owner of the todo, but working in the other workspace
+	elsewhere := Scope{UserID: a.UserID, WorkspaceID: b.WorkspaceID}
+
+	for name, scope := range map[string]Scope{"other user": b, "other workspace": elsewhere} {
+		if _, err := store.Get(mine.ID, scope); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("%s: Get() = %v, want ErrRecordNotFound", name, err)
+		}
+
+		changed := *mine
+		changed.Title = "Stolen"
+		if err := store.Update(&changed, scope); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("%s: Update() = %v, want ErrRecordNotFound", name, err)
+		}
+
+		if err := store.Delete(mine.ID, mine.Version, scope); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("%s: Delete() = %v, want ErrRecordNotFound", name, err)
+		}
+
+		todos, _, err := store.GetAll(scope, TodoSearch{}, Filters{Page: 1, PageSize: 100, Sort: "id", SortList: []string{"id"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, todo := range todos {
+			if todo.ID == mine.ID {
+				t.Errorf("%s: GetAll() listed a todo of another workspace", name)
+			}
+		}
+	}
+
+	got, err := store.Get(mine.ID, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != mine.Title || got.Version != mine.Version {
+		t.Errorf("todo changed to %q version %d", got.Title, got.Version)
+	}
+	if _, err := store.Get(theirs.ID, b); err != nil {
+		t.Errorf("Get() of the other workspace's own todo = %v", err)
+	}
+}
+
+func TestTagModelWorkspaceIsolation(t *testing.T) {
+	models := NewModels(testPostgres(t))
+	a, b := testRegister(t, models, "alice"), testRegister(t, models, "bob")
+
+	for _, scope := range []Scope{a, b} {
+		todo := &Todo{Title: "Call home", Status: StatusTodo, Priority: PriorityNormal, Tags: []string{"family"}, OwnerID: scope.UserID, WorkspaceID: scope.WorkspaceID}
+		if err := models.Todos.Insert(todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tagOf := func(scope Scope) *Tag {
+		tags, err := models.Tags.GetAll(scope.WorkspaceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tags) != 1 || tags[0].Name != "family" || tags[0].Todos != 1 {
+			t.Fatalf("workspace %d has tags %+v, want family on one todo", scope.WorkspaceID, tags)
+		}
+		return tags[0]
+	}
+	mine, theirs := tagOf(a), tagOf(b)
+	if mine.ID == theirs.ID {
+		t.Fatal("both workspaces share one tag")
+	}
+
+	if _, err := models.Tags.Get(mine.ID, b.WorkspaceID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Tags.Rename(&Tag{ID: mine.ID, Name: "stolen"}, b.WorkspaceID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Rename() = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Tags.Merge(mine.ID, theirs.ID, b.WorkspaceID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Merge() = %v, want ErrRecordNotFound", err)
+	}
+
+	//renaming in one workspace leaves the other's tag alone
+	if err := models.Tags.Rename(&Tag{ID: mine.ID, Name: "relatives"}, a.WorkspaceID); err != nil {
+		t.Fatal(err)
+	}
+	if tag := tagOf(b); tag.Name != "family" {
+		t.Errorf("other workspace's tag renamed to %q", tag.Name)
+	}
+}
+
+func TestListModelWorkspaceIsolation(t *testing.T) {
+	models := NewModels(testPostgres(t))
+	a, b := testRegister(t, models, "alice"), testRegister(t, models, "bob")
+	elsewhere := Scope{UserID: a.UserID, WorkspaceID: b.WorkspaceID}
+
+	list := &List{Name: "Groceries", OwnerID: a.UserID, WorkspaceID: a.WorkspaceID}
+	if err := models.Lists.Insert(list); err != nil {
+		t.Fatal(err)
+	}
+	//one todo on the list in its workspace and one that names it from elsewhere
+	for _, scope := range []Scope{a, elsewhere} {
+		todo := &Todo{Title: "Eggs", Status: StatusTodo, Priority: PriorityNormal, ListID: &list.ID, OwnerID: scope.UserID, WorkspaceID: scope.WorkspaceID}
+		if err := models.Todos.Insert(todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := models.Lists.Get(list.ID, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Todos != 1 {
+		t.Errorf("list counts %d todos, want only the one in its workspace", got.Todos)
+	}
+
+	if _, err := models.Lists.Get(list.ID, elsewhere); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() = %v, want ErrRecordNotFound", err)
+	}
+	lists, _, err := models.Lists.GetAll(elsewhere, Filters{Page: 1, PageSize: 100, Sort: "id", SortList: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 0 {
+		t.Errorf("GetAll() listed %d lists of another workspace", len(lists))
+	}
+	if err := models.Lists.Update(&List{ID: list.ID, Name: "Stolen", Version: list.Version}, elsewhere); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Update() = %v, want ErrRecordNotFound", err)
+	}
+	if err := models.Lists.Delete(list.ID, elsewhere); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Delete() = %v, want ErrRecordNotFound", err)
+	}
+}
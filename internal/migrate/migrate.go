@@ -0,0 +1,334 @@
+// File: todo/internal/migrate/migrate.go
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDirty is returned when an earlier migration failed part way. The schema has to
+	// be fixed by hand and the version set with Force() before migrating again
+	ErrDirty = errors.New("database is dirty")
+	// ErrNoVersion is returned by Goto() and Force() for a version with no migration
+	ErrNoVersion = errors.New("no such migration version")
+)
+
+// lockID is the Postgres advisory lock held while migrating, so that two instances
+// starting at once do not both apply the same migration
+const lockID = 7_135_406_112
+
+// Migration is one numbered step of the schema, read from a pair of
+// NNNNNN_name.up.sql and NNNNNN_name.down.sql files
+type Migration struct {
+	Version uint
+	Name    string
+	up      string
+	down    string
+}
+
+// Status describes the state of the database schema
+type Status struct {
+	Version    uint // 0 when no migration has been applied
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// Migrator applies the migrations embedded in the binary. The applied version is
+// kept in a schema_migrations table laid out as golang-migrate does, so databases
+// migrated by hand with that tool carry on from where they are
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+	Logger     *log.Logger
+}
+
+// New() reads the migrations in files. driver is "postgres" or "sqlite"
+func New(db *sql.DB, driver string, files fs.FS) (*Migrator, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, name := range names {
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", name)
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, "."+direction+".sql"), "_", 2)
+		version, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || version == 0 || len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", name)
+		}
+		script, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[uint(version)]
+		if migration == nil {
+			migration = &Migration{Version: uint(version), Name: parts[1]}
+			byVersion[uint(version)] = migration
+		}
+		if direction == "up" {
+			migration.up = string(script)
+		} else {
+			migration.down = string(script)
+		}
+	}
+
+	m := &Migrator{db: db, driver: driver}
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up file", migration.Version, migration.Name)
+		}
+		m.migrations = append(m.migrations, *migration)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return m, nil
+}
+
+// Up() applies every migration that has not been applied yet
+func (m *Migrator) Up() error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down() reverts the last steps applied migrations
+func (m *Migrator) Down(steps int) error {
+	return m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		current, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		target := current
+		for i := 0; i < steps && target > 0; i++ {
+			target = m.previous(target)
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Goto() migrates up or down to the given version. Version 0 reverts every migration
+func (m *Migrator) Goto(version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return ErrNoVersion
+	}
+	return m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		current, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, current, version)
+	})
+}
+
+// Force() records the version as applied and clean without running anything. It is
+// used to recover from a dirty state once the schema has been repaired
+func (m *Migrator) Force(version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return ErrNoVersion
+	}
+	return m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		m.logf("forcing version %d", version)
+		return m.setVersion(ctx, conn, version, false)
+	})
+}
+
+// Status() reports the applied version and which migrations it covers
+func (m *Migrator) Status() (Status, error) {
+	var status Status
+	err := m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = m.version(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	for _, migration := range m.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: migration.Version <= status.Version,
+		})
+	}
+	return status, nil
+}
+
+// locked() runs fn on a single connection holding the migration lock, after making
+// sure the schema_migrations table exists
+func (m *Migrator) locked(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	//SQLite is used by a single process with one connection and needs no lock
+	if m.driver == "postgres" {
+		if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	}
+
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`
+	if _, err = conn.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	return fn(ctx, conn)
+}
+
+// version() reads the applied version. No row means nothing has been applied
+func (m *Migrator) version(ctx context.Context, conn *sql.Conn) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+	//golang-migrate records -1 when every migration has been reverted
+	if version < 0 {
+		return 0, dirty, nil
+	}
+	return uint(version), dirty, nil
+}
+
+// current() reads the applied version, refusing to go on from a dirty one
+func (m *Migrator) current(ctx context.Context, conn *sql.Conn) (uint, error) {
+	version, dirty, err := m.version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d, fix the schema and force a version", ErrDirty, version)
+	}
+	return version, nil
+}
+
+// setVersion() replaces the single row of schema_migrations
+func (m *Migrator) setVersion(ctx context.Context, conn *sql.Conn, version uint, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 || dirty {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, int64(version), dirty)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// migrate() runs the up or down scripts between the current and target versions.
+// Each version is marked dirty while its script runs so that a failure is noticed
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target uint) error {
+	if current == target {
+		m.logf("no change, at version %d", current)
+		return nil
+	}
+
+	for _, migration := range m.migrations {
+		if current < target && migration.Version > current && migration.Version <= target {
+			m.logf("applying %d_%s", migration.Version, migration.Name)
+			if err := m.run(ctx, conn, migration.Version, migration.up, migration.Version); err != nil {
+				return err
+			}
+		}
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if current > target && migration.Version <= current && migration.Version > target {
+			if migration.down == "" {
+				return fmt.Errorf("migration %d_%s: missing down file", migration.Version, migration.Name)
+			}
+			m.logf("reverting %d_%s", migration.Version, migration.Name)
+			if err := m.run(ctx, conn, migration.Version, migration.down, m.previous(migration.Version)); err != nil {
+				return err
+			}
+		}
+	}
+	m.logf("at version %d", target)
+	return nil
+}
+
+// run() executes one script, leaving version marked dirty if it fails and
+// recording next as the clean version when it succeeds
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, version uint, script string, next uint) error {
+	if err := m.setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	return m.setVersion(ctx, conn, next, false)
+}
+
+// index() finds a migration by version, or returns -1
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// previous() returns the version of the migration before the given one, or 0
+func (m *Migrator) previous(version uint) uint {
+	previous := uint(0)
+	for _, migration := range m.migrations {
+		if migration.Version >= version {
+			break
+		}
+		previous = migration.Version
+	}
+	return previous
+}
+
+func (m *Migrator) logf(format string, args ...interface{}) {
+	if m.Logger != nil {
+		m.Logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,329 @@
+// File: todo/internal/recurrence/recurrence.go
+
+// Package recurrence implements the subset of RFC 5545 recurrence rules (RRULE)
+// used for repeating todos: FREQ, INTERVAL, BYDAY, BYMONTHDAY, UNTIL and COUNT.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a rule repeats
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// The layouts accepted for the UNTIL part
+const (
+	untilDateTime = "20060102T150405Z"
+	untilDate     = "20060102"
+)
+
+// The most candidate periods Next() will look through before giving up. This only
+// matters for rules that can rarely match, such as February 29th every year
+const maxPeriods = 1000
+
+var (
+	ErrEmptyRule        = errors.New("rule must not be empty")
+	ErrMissingFrequency = errors.New("FREQ must be provided")
+	ErrCountAndUntil    = errors.New("COUNT and UNTIL must not both be provided")
+)
+
+// The two letter weekday codes used by BYDAY
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Until      *time.Time
+	Count      int
+}
+
+// Parse() reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+// A leading "RRULE:" is allowed
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, ErrEmptyRule
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, found := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !found || val == "" {
+			return nil, fmt.Errorf("malformed part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s must not be repeated", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			freq := Frequency(val)
+			switch freq {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = freq
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, errors.New("INTERVAL must be a positive integer")
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, errors.New("COUNT must be a positive integer")
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, field := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(field)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY value %q must be between 1 and 31 or -31 and -1", field)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, ErrMissingFrequency
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, ErrCountAndUntil
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse(untilDateTime, value); err == nil {
+		return t, nil
+	}
+	// A plain date includes the whole of that day
+	if t, err := time.Parse(untilDate, value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %q must be a YYYYMMDD date or a YYYYMMDDTHHMMSSZ timestamp", value)
+}
+
+// String() writes the rule back out in its canonical form
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range sortedWeekdays(r.ByDay) {
+			for code, weekday := range weekdayCodes {
+				if weekday == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilDateTime))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next() returns the first occurrence strictly after from, treating from as an
+// occurrence of the rule. The wall clock time of from is kept in from's location:
+// in a location with daylight saving 09:00 stays 09:00 across the change, while a
+// time in UTC or a fixed offset keeps the same UTC time.
+// COUNT is not consulted here; see Advance()
+func (r Rule) Next(from time.Time) (time.Time, bool) {
+	var next time.Time
+	var ok bool
+
+	switch r.Freq {
+	case Daily:
+		next, ok = from.AddDate(0, 0, r.interval()), true
+	case Weekly:
+		next, ok = r.nextWeekly(from)
+	case Monthly:
+		next, ok = r.nextMonthly(from)
+	case Yearly:
+		next, ok = r.nextYearly(from)
+	}
+
+	if !ok || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// Advance() returns the occurrence after from along with the rule that the next
+// occurrence should carry. COUNT counts the remaining occurrences including the
+// current one, so it is decremented each time and the series ends when it reaches one
+func (r Rule) Advance(from time.Time) (time.Time, Rule, bool) {
+	if r.Count == 1 {
+		return time.Time{}, r, false
+	}
+	next, ok := r.Next(from)
+	if !ok {
+		return time.Time{}, r, false
+	}
+	rest := r
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return next, rest, true
+}
+
+func (r Rule) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// nextWeekly() walks the rest of the current week (weeks start on Monday) looking
+// for a matching weekday before jumping ahead by the interval
+func (r Rule) nextWeekly(from time.Time) (time.Time, bool) {
+	if len(r.ByDay) == 0 {
+		return from.AddDate(0, 0, 7*r.interval()), true
+	}
+
+	days := sortedWeekdays(r.ByDay)
+	offset := mondayOffset(from.Weekday())
+
+	for _, day := range days {
+		if d := mondayOffset(day); d > offset {
+			return from.AddDate(0, 0, d-offset), true
+		}
+	}
+
+	weekStart := from.AddDate(0, 0, -offset+7*r.interval())
+	return weekStart.AddDate(0, 0, mondayOffset(days[0])), true
+}
+
+// nextMonthly() looks at the current month and then every interval months after it.
+// Days that do not exist in a month (such as the 31st in April) are skipped as RFC 5545 requires
+func (r Rule) nextMonthly(from time.Time) (time.Time, bool) {
+	monthDays := r.ByMonthDay
+	if len(monthDays) == 0 {
+		monthDays = []int{from.Day()}
+	}
+	hour, min, sec := from.Clock()
+
+	for period := 0; period < maxPeriods; period++ {
+		// Always step from the first of the month so AddDate never overflows
+		first := time.Date(from.Year(), from.Month(), 1, hour, min, sec, from.Nanosecond(), from.Location())
+		first = first.AddDate(0, period*r.interval(), 0)
+		length := daysIn(first.Year(), first.Month())
+
+		days := make([]int, 0, len(monthDays))
+		for _, day := range monthDays {
+			if day < 0 {
+				day = length + day + 1
+			}
+			if day >= 1 && day <= length {
+				days = append(days, day)
+			}
+		}
+		sort.Ints(days)
+
+		for _, day := range days {
+			candidate := first.AddDate(0, 0, day-1)
+			if candidate.After(from) {
+				return candidate, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextYearly() repeats the same month and day, skipping years where it does not exist
+func (r Rule) nextYearly(from time.Time) (time.Time, bool) {
+	hour, min, sec := from.Clock()
+	for period := 1; period < maxPeriods; period++ {
+		year := from.Year() + period*r.interval()
+		if from.Day() > daysIn(year, from.Month()) {
+			continue
+		}
+		return time.Date(year, from.Month(), from.Day(), hour, min, sec, from.Nanosecond(), from.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// daysIn() returns the number of days in a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// mondayOffset() numbers the days of the week from Monday (0) to Sunday (6)
+func mondayOffset(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func sortedWeekdays(days []time.Weekday) []time.Weekday {
+	sorted := append([]time.Weekday(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return mondayOffset(sorted[i]) < mondayOffset(sorted[j])
+	})
+	return sorted
+}
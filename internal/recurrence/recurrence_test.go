@@ -0,0 +1,213 @@
+// File: todo/internal/recurrence/recurrence_test.go
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	_ "time/tzdata"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "daily", value: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{name: "rrule prefix and lower case", value: "RRULE:freq=weekly;byday=we,mo", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{name: "interval and count", value: "FREQ=MONTHLY;INTERVAL=3;COUNT=4", want: "FREQ=MONTHLY;INTERVAL=3;COUNT=4"},
+		{name: "interval of one is dropped", value: "FREQ=YEARLY;INTERVAL=1", want: "FREQ=YEARLY"},
+		{name: "until date covers the whole day", value: "FREQ=DAILY;UNTIL=20240131", want: "FREQ=DAILY;UNTIL=20240131T235959Z"},
+		{name: "until timestamp", value: "FREQ=DAILY;UNTIL=20240131T090000Z", want: "FREQ=DAILY;UNTIL=20240131T090000Z"},
+		{name: "last day of the month", value: "FREQ=MONTHLY;BYMONTHDAY=-1,15", want: "FREQ=MONTHLY;BYMONTHDAY=-1,15"},
+		{name: "empty", value: "  ", wantErr: true},
+		{name: "missing freq", value: "INTERVAL=2", wantErr: true},
+		{name: "unsupported freq", value: "FREQ=HOURLY", wantErr: true},
+		{name: "zero interval", value: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "repeated part", value: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "count and until", value: "FREQ=DAILY;COUNT=2;UNTIL=20240101", wantErr: true},
+		{name: "byday needs weekly", value: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+		{name: "bymonthday needs monthly", value: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{name: "bymonthday out of range", value: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "bad weekday", value: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "malformed part", value: "FREQ=DAILY;COUNT", wantErr: true},
+		{name: "unsupported part", value: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %v, want an error", tt.value, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) returned %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		from   time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "daily", rule: "FREQ=DAILY", from: date(2024, 1, 1, 9, 0), want: date(2024, 1, 2, 9, 0), wantOK: true},
+		{name: "every third day across a month", rule: "FREQ=DAILY;INTERVAL=3", from: date(2024, 1, 30, 9, 0), want: date(2024, 2, 2, 9, 0), wantOK: true},
+		{name: "weekly", rule: "FREQ=WEEKLY", from: date(2024, 1, 1, 9, 0), want: date(2024, 1, 8, 9, 0), wantOK: true},
+		{name: "weekly later in the same week", rule: "FREQ=WEEKLY;BYDAY=MO,FR", from: date(2024, 1, 1, 9, 0), want: date(2024, 1, 5, 9, 0), wantOK: true},
+		{name: "weekly wraps to next week", rule: "FREQ=WEEKLY;BYDAY=MO,FR", from: date(2024, 1, 5, 9, 0), want: date(2024, 1, 8, 9, 0), wantOK: true},
+		{name: "fortnightly wraps two weeks", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", from: date(2024, 1, 5, 9, 0), want: date(2024, 1, 15, 9, 0), wantOK: true},
+		{name: "sunday ends the week", rule: "FREQ=WEEKLY;BYDAY=SU,MO", from: date(2024, 1, 1, 9, 0), want: date(2024, 1, 7, 9, 0), wantOK: true},
+		{name: "monthly", rule: "FREQ=MONTHLY", from: date(2024, 1, 15, 9, 0), want: date(2024, 2, 15, 9, 0), wantOK: true},
+		{name: "31st skips short months", rule: "FREQ=MONTHLY", from: date(2024, 1, 31, 9, 0), want: date(2024, 3, 31, 9, 0), wantOK: true},
+		{name: "31st skips april", rule: "FREQ=MONTHLY;BYMONTHDAY=31", from: date(2024, 3, 31, 9, 0), want: date(2024, 5, 31, 9, 0), wantOK: true},
+		{name: "last day in a leap february", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", from: date(2024, 1, 31, 9, 0), want: date(2024, 2, 29, 9, 0), wantOK: true},
+		{name: "last day in a common february", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", from: date(2023, 1, 31, 9, 0), want: date(2023, 2, 28, 9, 0), wantOK: true},
+		{name: "last day after february", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", from: date(2024, 2, 29, 9, 0), want: date(2024, 3, 31, 9, 0), wantOK: true},
+		{name: "several days in a month", rule: "FREQ=MONTHLY;BYMONTHDAY=1,15", from: date(2024, 1, 1, 9, 0), want: date(2024, 1, 15, 9, 0), wantOK: true},
+		{name: "quarterly skips a short february", rule: "FREQ=MONTHLY;INTERVAL=3", from: date(2024, 11, 30, 9, 0), want: date(2025, 5, 30, 9, 0), wantOK: true},
+		{name: "yearly", rule: "FREQ=YEARLY", from: date(2024, 3, 1, 9, 0), want: date(2025, 3, 1, 9, 0), wantOK: true},
+		{name: "leap day waits for the next leap year", rule: "FREQ=YEARLY", from: date(2024, 2, 29, 9, 0), want: date(2028, 2, 29, 9, 0), wantOK: true},
+		{name: "until allows the last day", rule: "FREQ=DAILY;UNTIL=20240102", from: date(2024, 1, 1, 9, 0), want: date(2024, 1, 2, 9, 0), wantOK: true},
+		{name: "until ends the series", rule: "FREQ=DAILY;UNTIL=20240102T080000Z", from: date(2024, 1, 1, 9, 0), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := rule.Next(tt.from)
+			if ok != tt.wantOK {
+				t.Fatalf("Next(%v) ok = %v, want %v", tt.from, ok, tt.wantOK)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		from time.Time
+		want time.Time
+	}{
+		//clocks go forward on 10 March 2024 and back on 3 November 2024
+		{
+			name: "daily across spring forward",
+			rule: "FREQ=DAILY",
+			from: time.Date(2024, 3, 9, 9, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 10, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "weekly across fall back",
+			rule: "FREQ=WEEKLY",
+			from: time.Date(2024, 10, 28, 9, 0, 0, 0, newYork),
+			want: time.Date(2024, 11, 4, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "monthly across spring forward",
+			rule: "FREQ=MONTHLY",
+			from: time.Date(2024, 2, 15, 9, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 15, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "utc keeps the utc time",
+			rule: "FREQ=DAILY",
+			from: time.Date(2024, 3, 9, 14, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 10, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := rule.Next(tt.from)
+			if !ok || !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) = %v, %v, want %v", tt.from, got, ok, tt.want)
+			}
+			if got.Location() != tt.from.Location() {
+				t.Errorf("Next() moved from %v to %v", tt.from.Location(), got.Location())
+			}
+		})
+	}
+
+	//the wall clock is kept, so the gap is an hour short across spring forward
+	if gap := tests[0].want.Sub(tests[0].from); gap != 23*time.Hour {
+		t.Errorf("spring forward gap = %v, want 23h", gap)
+	}
+}
+
+func TestAdvanceCount(t *testing.T) {
+	rule, err := Parse("FREQ=DAILY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := date(2024, 1, 1, 9, 0)
+	var dates []time.Time
+	for {
+		next, rest, ok := rule.Advance(from)
+		if !ok {
+			break
+		}
+		dates = append(dates, next)
+		from, rule = next, &rest
+		if len(dates) > 10 {
+			t.Fatal("series did not end")
+		}
+	}
+
+	//COUNT=3 includes the first occurrence, so two more follow it
+	want := []time.Time{date(2024, 1, 2, 9, 0), date(2024, 1, 3, 9, 0)}
+	if len(dates) != len(want) {
+		t.Fatalf("got %d occurrences %v, want %v", len(dates), dates, want)
+	}
+	for i := range want {
+		if !dates[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %v, want %v", i, dates[i], want[i])
+		}
+	}
+	if rule.Count != 1 {
+		t.Errorf("remaining count = %d, want 1", rule.Count)
+	}
+}
+
+func TestAdvanceUnlimited(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rest, ok := rule.Advance(date(2024, 1, 1, 9, 0))
+	if !ok || rest.Count != 0 {
+		t.Errorf("Advance() = %v, %v; a rule without COUNT should go on", rest, ok)
+	}
+}
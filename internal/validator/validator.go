@@ -3,8 +3,12 @@ package validator
 
 import (
 	"net/url"
+	"regexp"
 )
 
+// EmailRX is a regular expression for sanity checking the format of email addresses
+var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
 // We create a type that wraps our validation errors map
 type Validator struct {
 	Errors map[string]string
@@ -32,6 +36,11 @@ func In(element string, list ...string) bool {
 	return false
 }
 
+// Matches() returns true if a string value matches a specific regexp pattern
+func Matches(value string, rx *regexp.Regexp) bool {
+	return rx.MatchString(value)
+}
+
 // ValidWebsite() checks if a string value is a valid web URL
 func ValidWebsite(website string) bool {
 	_, err := url.ParseRequestURI(website)
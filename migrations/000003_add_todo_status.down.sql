@@ -0,0 +1,9 @@
+--File: migrations/000003_add_todo_status.down.sql
+drop index if exists todos_status_idx;
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS Done text;
+UPDATE todos SET Done = CASE WHEN Status = 'done' THEN 'true' ELSE 'false' END;
+
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_status_check;
+ALTER TABLE todos DROP COLUMN IF EXISTS CompletedAt;
+ALTER TABLE todos DROP COLUMN IF EXISTS Status;
@@ -0,0 +1,13 @@
+--File: migrations/000003_add_todo_status.up.sql
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS Status text NOT NULL DEFAULT 'todo';
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS CompletedAt timestamp(0) with time zone;
+
+UPDATE todos SET Status = 'done', CompletedAt = NOW()
+WHERE lower(trim(Done)) IN ('yes', 'y', 'true', 't', '1', 'done', 'complete', 'completed');
+
+ALTER TABLE todos ADD CONSTRAINT todos_status_check
+CHECK (Status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled'));
+
+ALTER TABLE todos DROP COLUMN IF EXISTS Done;
+
+create index if not exists todos_status_idx on todos (Status);
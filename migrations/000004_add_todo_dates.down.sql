@@ -0,0 +1,6 @@
+--File: migrations/000004_add_todo_dates.down.sql
+drop index if exists todos_dueat_idx;
+
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_dates_check;
+ALTER TABLE todos DROP COLUMN IF EXISTS DueAt;
+ALTER TABLE todos DROP COLUMN IF EXISTS StartsAt;
@@ -0,0 +1,8 @@
+--File: migrations/000004_add_todo_dates.up.sql
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS StartsAt timestamp(0) with time zone;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS DueAt timestamp(0) with time zone;
+
+ALTER TABLE todos ADD CONSTRAINT todos_dates_check
+CHECK (DueAt IS NULL OR StartsAt IS NULL OR DueAt >= StartsAt);
+
+create index if not exists todos_dueat_idx on todos (DueAt);
@@ -0,0 +1,5 @@
+--File: migrations/000005_add_todo_priority.down.sql
+drop index if exists todos_priority_dueat_idx;
+
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_priority_check;
+ALTER TABLE todos DROP COLUMN IF EXISTS Priority;
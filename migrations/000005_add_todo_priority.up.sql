@@ -0,0 +1,6 @@
+--File: migrations/000005_add_todo_priority.up.sql
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS Priority smallint NOT NULL DEFAULT 2;
+
+ALTER TABLE todos ADD CONSTRAINT todos_priority_check CHECK (Priority BETWEEN 1 AND 4);
+
+create index if not exists todos_priority_dueat_idx on todos (Priority DESC, DueAt ASC);
@@ -0,0 +1,3 @@
+--File: migrations/000006_create_tags_table.down.sql
+drop table if exists todo_tags;
+drop table if exists tags;
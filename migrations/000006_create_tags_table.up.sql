@@ -0,0 +1,14 @@
+--File: migrations/000006_create_tags_table.up.sql
+CREATE TABLE IF NOT EXISTS tags(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    Name text UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS todo_tags(
+    todo_id bigint NOT NULL REFERENCES todos ON DELETE CASCADE,
+    tag_id bigint NOT NULL REFERENCES tags ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+create index if not exists todo_tags_tag_id_idx on todo_tags (tag_id);
@@ -0,0 +1,2 @@
+--File: migrations/000007_create_todo_items_table.down.sql
+drop table if exists todo_items;
@@ -0,0 +1,12 @@
+--File: migrations/000007_create_todo_items_table.up.sql
+CREATE TABLE IF NOT EXISTS todo_items(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    todo_id bigint NOT NULL REFERENCES todos ON DELETE CASCADE,
+    Title text NOT NULL,
+    Done boolean NOT NULL DEFAULT false,
+    Position integer NOT NULL DEFAULT 0,
+    Version integer NOT NULL DEFAULT 1
+);
+
+create index if not exists todo_items_todo_id_idx on todo_items (todo_id, Position);
@@ -0,0 +1,6 @@
+--File: migrations/000008_create_lists_table.down.sql
+drop index if exists todos_list_id_idx;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS list_id;
+
+drop table if exists lists;
@@ -0,0 +1,12 @@
+--File: migrations/000008_create_lists_table.up.sql
+CREATE TABLE IF NOT EXISTS lists(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    Name text NOT NULL,
+    Description text NOT NULL DEFAULT '',
+    Version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS list_id bigint REFERENCES lists ON DELETE SET NULL;
+
+create index if not exists todos_list_id_idx on todos (list_id);
@@ -0,0 +1,2 @@
+--File: migrations/000009_add_todo_recurrence.down.sql
+ALTER TABLE todos DROP COLUMN IF EXISTS Recurrence;
@@ -0,0 +1,2 @@
+--File: migrations/000009_add_todo_recurrence.up.sql
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS Recurrence text NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+--File: migrations/000010_create_users_table.down.sql
+drop index if exists todos_owner_id_idx;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS owner_id;
+
+drop table if exists users;
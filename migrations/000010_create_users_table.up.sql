@@ -0,0 +1,15 @@
+--File: migrations/000010_create_users_table.up.sql
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE IF NOT EXISTS users(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    Name text NOT NULL,
+    Email citext UNIQUE NOT NULL,
+    password_hash bytea NOT NULL,
+    Version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS owner_id bigint REFERENCES users ON DELETE CASCADE;
+
+create index if not exists todos_owner_id_idx on todos (owner_id);
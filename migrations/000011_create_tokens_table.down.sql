@@ -0,0 +1,2 @@
+--File: migrations/000011_create_tokens_table.down.sql
+drop table if exists tokens;
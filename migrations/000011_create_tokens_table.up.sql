@@ -0,0 +1,7 @@
+--File: migrations/000011_create_tokens_table.up.sql
+CREATE TABLE IF NOT EXISTS tokens(
+    hash bytea PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    expiry timestamp(0) with time zone NOT NULL,
+    scope text NOT NULL
+);
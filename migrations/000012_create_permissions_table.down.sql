@@ -0,0 +1,3 @@
+--File: migrations/000012_create_permissions_table.down.sql
+drop table if exists users_permissions;
+drop table if exists permissions;
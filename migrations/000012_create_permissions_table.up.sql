@@ -0,0 +1,15 @@
+--File: migrations/000012_create_permissions_table.up.sql
+CREATE TABLE IF NOT EXISTS permissions(
+    ID bigserial PRIMARY KEY,
+    Code text UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users_permissions(
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT INTO permissions (Code)
+VALUES ('todos:read'), ('todos:write'), ('todos:admin')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+--File: migrations/000013_create_shares_tables.down.sql
+drop table if exists list_shares;
+drop table if exists todo_shares;
+
+drop index if exists lists_owner_id_idx;
+
+ALTER TABLE lists DROP COLUMN IF EXISTS owner_id;
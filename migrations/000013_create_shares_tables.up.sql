@@ -0,0 +1,23 @@
+--File: migrations/000013_create_shares_tables.up.sql
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS owner_id bigint REFERENCES users ON DELETE CASCADE;
+
+create index if not exists lists_owner_id_idx on lists (owner_id);
+
+CREATE TABLE IF NOT EXISTS todo_shares(
+    todo_id bigint NOT NULL REFERENCES todos ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    access text NOT NULL CHECK (access IN ('view', 'edit')),
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS list_shares(
+    list_id bigint NOT NULL REFERENCES lists ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    access text NOT NULL CHECK (access IN ('view', 'edit')),
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (list_id, user_id)
+);
+
+create index if not exists todo_shares_user_id_idx on todo_shares (user_id);
+create index if not exists list_shares_user_id_idx on list_shares (user_id);
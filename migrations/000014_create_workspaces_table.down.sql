@@ -0,0 +1,7 @@
+--File: migrations/000014_create_workspaces_table.down.sql
+drop index if exists todos_workspace_id_idx;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS workspace_id;
+
+drop table if exists workspace_members;
+drop table if exists workspaces;
@@ -0,0 +1,36 @@
+--File: migrations/000014_create_workspaces_table.up.sql
+CREATE TABLE IF NOT EXISTS workspaces(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    Name text NOT NULL,
+    Version integer NOT NULL DEFAULT 1,
+    -- only used to match the personal workspaces created below to their users
+    personal_owner_id bigint UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members(
+    workspace_id bigint NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('owner', 'member')),
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+create index if not exists workspace_members_user_id_idx on workspace_members (user_id);
+
+-- every existing user gets a personal workspace holding the todos they own
+INSERT INTO workspaces (Name, personal_owner_id)
+SELECT 'Personal', ID FROM users;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT ID, personal_owner_id, 'owner' FROM workspaces WHERE personal_owner_id IS NOT NULL;
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS workspace_id bigint REFERENCES workspaces ON DELETE CASCADE;
+
+UPDATE todos SET workspace_id = workspaces.ID
+FROM workspaces
+WHERE workspaces.personal_owner_id = todos.owner_id;
+
+ALTER TABLE workspaces DROP COLUMN personal_owner_id;
+
+create index if not exists todos_workspace_id_idx on todos (workspace_id);
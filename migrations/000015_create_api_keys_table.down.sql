@@ -0,0 +1,2 @@
+--File: migrations/000015_create_api_keys_table.down.sql
+drop table if exists api_keys;
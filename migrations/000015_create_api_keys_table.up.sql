@@ -0,0 +1,16 @@
+--File: migrations/000015_create_api_keys_table.up.sql
+CREATE TABLE IF NOT EXISTS api_keys(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    Name text NOT NULL,
+    hash bytea UNIQUE NOT NULL,
+    Hint text NOT NULL,
+    Scopes text[] NOT NULL,
+    Expiry timestamp(0) with time zone,
+    LastUsedAt timestamp(0) with time zone,
+    RevokedAt timestamp(0) with time zone,
+    Version integer NOT NULL DEFAULT 1
+);
+
+create index if not exists api_keys_user_id_idx on api_keys (user_id);
@@ -0,0 +1,4 @@
+--File: migrations/000016_add_todo_deleted_at.down.sql
+DELETE FROM todos WHERE DeletedAt IS NOT NULL;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS DeletedAt;
@@ -0,0 +1,4 @@
+--File: migrations/000016_add_todo_deleted_at.up.sql
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS DeletedAt timestamp(0) with time zone;
+
+create index if not exists todos_deletedat_idx on todos (DeletedAt) WHERE DeletedAt IS NOT NULL;
@@ -0,0 +1,2 @@
+--File: migrations/000017_create_todo_revisions_table.down.sql
+drop table if exists todo_revisions;
@@ -0,0 +1,13 @@
+--File: migrations/000017_create_todo_revisions_table.up.sql
+CREATE TABLE IF NOT EXISTS todo_revisions(
+    ID bigserial PRIMARY KEY,
+    CreatedAt timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    todo_id bigint NOT NULL REFERENCES todos ON DELETE CASCADE,
+    Version integer NOT NULL,
+    Action text NOT NULL CHECK (Action IN ('create', 'update', 'delete', 'restore')),
+    actor_id bigint REFERENCES users ON DELETE SET NULL,
+    Changes jsonb NOT NULL DEFAULT '{}',
+    Snapshot jsonb NOT NULL
+);
+
+create index if not exists todo_revisions_todo_id_idx on todo_revisions (todo_id, Version);
@@ -0,0 +1,2 @@
+--File: migrations/000018_add_todo_next_id.down.sql
+ALTER TABLE todos DROP COLUMN IF EXISTS next_id;
@@ -0,0 +1,2 @@
+--File: migrations/000018_add_todo_next_id.up.sql
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS next_id bigint REFERENCES todos ON DELETE SET NULL;
@@ -0,0 +1,16 @@
+--File: migrations/000019_add_workspace_to_tags_and_lists.down.sql
+drop index if exists lists_workspace_id_idx;
+
+ALTER TABLE lists DROP COLUMN IF EXISTS workspace_id;
+
+-- tags with the same name are folded back into the oldest one
+UPDATE todo_tags SET tag_id = keep.ID
+FROM tags, (SELECT MIN(ID) AS ID, Name FROM tags GROUP BY Name) keep
+WHERE tags.ID = todo_tags.tag_id
+AND keep.Name = tags.Name AND keep.ID <> tags.ID;
+
+DELETE FROM tags WHERE ID NOT IN (SELECT MIN(ID) FROM tags GROUP BY Name);
+
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_workspace_id_name_key;
+ALTER TABLE tags DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (Name);
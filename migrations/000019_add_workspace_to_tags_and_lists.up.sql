@@ -0,0 +1,43 @@
+--File: migrations/000019_add_workspace_to_tags_and_lists.up.sql
+-- tags and lists were shared by everyone, now each belongs to a single workspace
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS workspace_id bigint REFERENCES workspaces ON DELETE CASCADE;
+
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+ALTER TABLE tags ADD CONSTRAINT tags_workspace_id_name_key UNIQUE (workspace_id, Name);
+
+-- every workspace gets its own copy of the tags its todos carry
+INSERT INTO tags (Name, workspace_id)
+SELECT DISTINCT tags.Name, todos.workspace_id
+FROM tags
+JOIN todo_tags ON todo_tags.tag_id = tags.ID
+JOIN todos ON todos.ID = todo_tags.todo_id
+WHERE tags.workspace_id IS NULL AND todos.workspace_id IS NOT NULL;
+
+UPDATE todo_tags SET tag_id = copies.ID
+FROM tags, todos, tags copies
+WHERE tags.ID = todo_tags.tag_id AND tags.workspace_id IS NULL
+AND todos.ID = todo_tags.todo_id
+AND copies.workspace_id = todos.workspace_id AND copies.Name = tags.Name;
+
+-- the shared tags that are left only label unclaimed todos
+DELETE FROM tags
+WHERE workspace_id IS NULL
+AND NOT EXISTS (SELECT 1 FROM todo_tags WHERE todo_tags.tag_id = tags.ID);
+
+-- a list moves to its owner's first workspace
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS workspace_id bigint REFERENCES workspaces ON DELETE CASCADE;
+
+UPDATE lists SET workspace_id = (
+    SELECT workspace_id FROM workspace_members
+    WHERE workspace_members.user_id = lists.owner_id
+    ORDER BY workspace_id ASC
+    LIMIT 1
+);
+
+-- todos from another workspace are taken off the list
+UPDATE todos SET list_id = NULL
+FROM lists
+WHERE lists.ID = todos.list_id
+AND lists.workspace_id IS DISTINCT FROM todos.workspace_id;
+
+create index if not exists lists_workspace_id_idx on lists (workspace_id);
@@ -0,0 +1,3 @@
+--File: migrations/000020_grant_existing_users_todo_permissions.down.sql
+-- the grants cannot be told apart from those given at registration, so they are kept
+SELECT 1;
@@ -0,0 +1,6 @@
+--File: migrations/000020_grant_existing_users_todo_permissions.up.sql
+-- users who registered before permissions existed keep reading and writing todos
+INSERT INTO users_permissions
+SELECT users.id, permissions.id FROM users, permissions
+WHERE permissions.Code IN ('todos:read', 'todos:write')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+--File: migrations/000021_comment_todo_owner.down.sql
+COMMENT ON COLUMN todos.owner_id IS NULL;
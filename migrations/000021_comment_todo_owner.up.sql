@@ -0,0 +1,2 @@
+--File: migrations/000021_comment_todo_owner.up.sql
+COMMENT ON COLUMN todos.owner_id IS 'NULL for todos created before there were accounts; no query reaches them until they are claimed with `api claim-todos EMAIL`';
@@ -0,0 +1,25 @@
+// File: todo/migrations/embed.go
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Postgres holds the schema of the Postgres storage backend
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+// SQLite holds the schema of the SQLite storage backend
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
+
+// Files() returns the migrations for a database driver
+func Files(driver string) (fs.FS, error) {
+	if driver == "sqlite" {
+		return fs.Sub(SQLite, "sqlite")
+	}
+	return Postgres, nil
+}
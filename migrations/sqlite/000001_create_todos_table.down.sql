@@ -0,0 +1,2 @@
+--File: migrations/sqlite/000001_create_todos_table.down.sql
+drop table if exists todos;
@@ -0,0 +1,22 @@
+--File: migrations/sqlite/000001_create_todos_table.up.sql
+CREATE TABLE IF NOT EXISTS todos(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    createdat TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'todo' CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled')),
+    priority INTEGER NOT NULL DEFAULT 2 CHECK (priority BETWEEN 1 AND 4),
+    completedat TEXT,
+    startsat TEXT,
+    dueat TEXT,
+    deletedat TEXT,
+    recurrence TEXT NOT NULL DEFAULT '',
+    owner_id INTEGER NOT NULL,
+    workspace_id INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (dueat IS NULL OR startsat IS NULL OR dueat >= startsat)
+);
+
+create index if not exists todos_owner_id_idx on todos (owner_id, workspace_id);
+create index if not exists todos_dueat_idx on todos (dueat);
+create index if not exists todos_deletedat_idx on todos (deletedat) WHERE deletedat IS NOT NULL;
@@ -0,0 +1,3 @@
+--File: migrations/sqlite/000002_create_todo_tags_table.down.sql
+drop trigger if exists todos_delete_tags;
+drop table if exists todo_tags;
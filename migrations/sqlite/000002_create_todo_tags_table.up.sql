@@ -0,0 +1,13 @@
+--File: migrations/sqlite/000002_create_todo_tags_table.up.sql
+CREATE TABLE IF NOT EXISTS todo_tags(
+    todo_id INTEGER NOT NULL REFERENCES todos ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    PRIMARY KEY (todo_id, name)
+);
+
+create index if not exists todo_tags_name_idx on todo_tags (name);
+
+-- foreign keys are off by default in SQLite so the cascade is done by hand
+CREATE TRIGGER IF NOT EXISTS todos_delete_tags AFTER DELETE ON todos BEGIN
+    DELETE FROM todo_tags WHERE todo_id = old.id;
+END;
@@ -0,0 +1,5 @@
+--File: migrations/sqlite/000003_create_todos_fts_table.down.sql
+drop trigger if exists todos_fts_update;
+drop trigger if exists todos_fts_delete;
+drop trigger if exists todos_fts_insert;
+drop table if exists todos_fts;
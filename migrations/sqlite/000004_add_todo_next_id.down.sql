@@ -0,0 +1,3 @@
+--File: migrations/sqlite/000004_add_todo_next_id.down.sql
+drop trigger if exists todos_clear_next_id;
+ALTER TABLE todos DROP COLUMN next_id;
@@ -0,0 +1,7 @@
+--File: migrations/sqlite/000004_add_todo_next_id.up.sql
+ALTER TABLE todos ADD COLUMN next_id INTEGER;
+
+-- like the tags, the link to a purged next occurrence is cleared by hand
+CREATE TRIGGER IF NOT EXISTS todos_clear_next_id AFTER DELETE ON todos BEGIN
+    UPDATE todos SET next_id = NULL WHERE next_id = old.id;
+END;